@@ -0,0 +1,152 @@
+package ctx
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Principal is the authenticated client of the current request.
+type Principal struct {
+	// ID is the user name, the owner of the api key or the subject of jwt.
+	ID string
+	// Method is the authentication method, e.g. "basic", "apikey", "jwt".
+	Method      string
+	Roles       []string
+	Scopes      []string
+	Permissions []string
+	// Claims is the claims of jwt.
+	Claims Map
+}
+
+// Principal returns the authenticated client of the current request, nil if
+// the client is not authenticated.
+func (c *Context) Principal() *Principal {
+	return c.principal
+}
+
+// SetPrincipal sets the authenticated client of the current request, for
+// your own authentication middleware.
+func (c *Context) SetPrincipal(p *Principal) {
+	c.principal = p
+}
+
+// BasicAuthConfig is the config of BasicAuth.
+type BasicAuthConfig struct {
+	// Realm is the realm of the WWW-Authenticate header, default is
+	// "Restricted".
+	Realm string
+	// Users is the passwords by user name.
+	Users map[string]string
+	// Validate validates the users not in Users, it returns nil if the user
+	// is invalid.
+	Validate func(c *Context, user, pass string) *Principal
+}
+
+// BasicAuth returns the HTTP Basic authentication middleware. It returns a
+// 401 HTTPError with WWW-Authenticate if the authentication fails.
+func BasicAuth(cfg *BasicAuthConfig) Handler {
+	conf := *cfg
+	if conf.Realm == "" {
+		conf.Realm = "Restricted"
+	}
+	challenge := "Basic realm=" + strconv.Quote(conf.Realm) +
+		`, charset="UTF-8"`
+	return func(c *Context) error {
+		if user, pass, ok := c.Req.BasicAuth(); ok {
+			if p := conf.validate(c, user, pass); p != nil {
+				c.SetPrincipal(p)
+				return nil
+			}
+		}
+		c.ResHeader().Set("WWW-Authenticate", challenge)
+		return NewHTTPError(http.StatusUnauthorized)
+	}
+}
+
+// validate returns the principal of user, nil if invalid.
+func (conf *BasicAuthConfig) validate(c *Context, user, pass string) *Principal {
+	ok := false
+	for u, p := range conf.Users {
+		// compare all users in constant time
+		if secureCompare(u, user) && secureCompare(p, pass) {
+			ok = true
+		}
+	}
+	if ok {
+		return &Principal{ID: user, Method: "basic"}
+	}
+	if conf.Validate != nil {
+		return conf.Validate(c, user, pass)
+	}
+	return nil
+}
+
+// APIKeyConfig is the config of APIKey.
+type APIKeyConfig struct {
+	// Header is the request header of the key, default is "X-API-Key". The
+	// "Bearer " prefix is removed, so it can be "Authorization".
+	Header string
+	// Query is the query parameter of the key, empty means the key is only
+	// read from Header.
+	Query string
+	// Keys is the principals by api key.
+	Keys map[string]*Principal
+	// Validate validates the keys not in Keys, it returns nil if the key is
+	// invalid.
+	Validate func(c *Context, key string) *Principal
+}
+
+// APIKey returns the api key authentication middleware. It returns a 401
+// HTTPError if the authentication fails.
+func APIKey(cfg *APIKeyConfig) Handler {
+	conf := *cfg
+	if conf.Header == "" {
+		conf.Header = "X-API-Key"
+	}
+	return func(c *Context) error {
+		key := c.ReqHeader().Get(conf.Header)
+		if len(key) > 7 && strings.EqualFold(key[:7], "Bearer ") {
+			key = key[7:]
+		}
+		if key == "" && conf.Query != "" {
+			key = c.Req.URL.Query().Get(conf.Query)
+		}
+		if key != "" {
+			if p := conf.validate(c, key); p != nil {
+				c.SetPrincipal(p)
+				return nil
+			}
+		}
+		return NewHTTPError(http.StatusUnauthorized)
+	}
+}
+
+// validate returns the principal of key, nil if invalid.
+func (conf *APIKeyConfig) validate(c *Context, key string) *Principal {
+	var principal *Principal
+	for k, p := range conf.Keys {
+		// compare all keys in constant time
+		if secureCompare(k, key) {
+			principal = p
+		}
+	}
+	if principal != nil {
+		cp := *principal
+		cp.Method = "apikey"
+		return &cp
+	}
+	if conf.Validate != nil {
+		return conf.Validate(c, key)
+	}
+	return nil
+}
+
+// secureCompare compares a and b in constant time, the lengths are not
+// leaked either.
+func secureCompare(a, b string) bool {
+	ha, hb := sha256.Sum256([]byte(a)), sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
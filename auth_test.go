@@ -0,0 +1,82 @@
+package ctx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func authRequest(target string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	res := httptest.NewRecorder()
+	routerIns.r.ServeHTTP(res, req)
+	return res
+}
+
+var whoami = func(c *Context) error {
+	return c.String(c.Principal().Method + ":" + c.Principal().ID)
+}
+
+func TestBasicAuth(t *testing.T) {
+	resetRouter()
+	GET("/", whoami, BasicAuth(&BasicAuthConfig{
+		Realm: "admin",
+		Users: map[string]string{"alice": "secret"},
+		Validate: func(c *Context, user, pass string) *Principal {
+			if user == "bob" && pass == "pass" {
+				return &Principal{ID: "bob", Method: "custom"}
+			}
+			return nil
+		},
+	}))
+
+	res := authRequest("/", nil)
+	assert.Equal(t, 401, res.Code)
+	assert.Equal(
+		t,
+		`Basic realm="admin", charset="UTF-8"`,
+		res.Header().Get("WWW-Authenticate"),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth("alice", "secret")
+	res = authRequest("/", map[string]string{"Authorization": req.Header.Get("Authorization")})
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "basic:alice", res.Body.String())
+
+	req.SetBasicAuth("alice", "wrong")
+	res = authRequest("/", map[string]string{"Authorization": req.Header.Get("Authorization")})
+	assert.Equal(t, 401, res.Code)
+
+	req.SetBasicAuth("bob", "pass")
+	res = authRequest("/", map[string]string{"Authorization": req.Header.Get("Authorization")})
+	assert.Equal(t, "custom:bob", res.Body.String())
+}
+
+func TestAPIKey(t *testing.T) {
+	resetRouter()
+	GET("/", whoami, APIKey(&APIKeyConfig{
+		Query: "key",
+		Keys:  map[string]*Principal{"k1": {ID: "svc"}},
+	}))
+	GET("/bearer", whoami, APIKey(&APIKeyConfig{
+		Header: "Authorization",
+		Keys:   map[string]*Principal{"k1": {ID: "svc"}},
+	}))
+
+	res := authRequest("/", map[string]string{"X-API-Key": "k1"})
+	assert.Equal(t, "apikey:svc", res.Body.String())
+	res = authRequest("/?key=k1", nil)
+	assert.Equal(t, "apikey:svc", res.Body.String())
+	res = authRequest("/?key=k2", nil)
+	assert.Equal(t, 401, res.Code)
+	res = authRequest("/", nil)
+	assert.Equal(t, 401, res.Code)
+	res = authRequest("/bearer", map[string]string{"Authorization": "Bearer k1"})
+	assert.Equal(t, "apikey:svc", res.Body.String())
+}
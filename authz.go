@@ -0,0 +1,142 @@
+package ctx
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Policy decides if the principal of the current request is allowed.
+type Policy interface {
+	Allow(c *Context, p *Principal) bool
+	// String describes the policy in the route introspection.
+	String() string
+}
+
+// Roles returns a Policy which allows the principal with any of roles.
+func Roles(roles ...string) Policy {
+	return &listPolicy{kind: "role", values: roles, any: true}
+}
+
+// Scopes returns a Policy which allows the principal with all of scopes.
+func Scopes(scopes ...string) Policy {
+	return &listPolicy{kind: "scope", values: scopes}
+}
+
+// Permissions returns a Policy which allows the principal with all of
+// perms.
+func Permissions(perms ...string) Policy {
+	return &listPolicy{kind: "permission", values: perms}
+}
+
+// PolicyFunc returns a Policy named `name` which allows the principal if f
+// returns true.
+func PolicyFunc(name string, f func(c *Context, p *Principal) bool) Policy {
+	return &funcPolicy{name: name, f: f}
+}
+
+// listPolicy checks the roles, scopes or permissions of the principal.
+type listPolicy struct {
+	kind   string
+	values []string
+	any    bool
+}
+
+// Allow implements the Policy interface.
+func (lp *listPolicy) Allow(c *Context, p *Principal) bool {
+	var has []string
+	switch lp.kind {
+	case "role":
+		has = p.Roles
+	case "scope":
+		has = p.Scopes
+	case "permission":
+		has = p.Permissions
+	}
+	for _, v := range lp.values {
+		found := false
+		for _, h := range has {
+			if h == v {
+				found = true
+				break
+			}
+		}
+		if found && lp.any {
+			return true
+		}
+		if !found && !lp.any {
+			return false
+		}
+	}
+	return !lp.any
+}
+
+// String implements the Policy interface.
+func (lp *listPolicy) String() string {
+	sep := ","
+	if lp.any {
+		sep = "|"
+	}
+	return lp.kind + ":" + strings.Join(lp.values, sep)
+}
+
+// funcPolicy is the Policy of PolicyFunc.
+type funcPolicy struct {
+	name string
+	f    func(c *Context, p *Principal) bool
+}
+
+// Allow implements the Policy interface.
+func (fp *funcPolicy) Allow(c *Context, p *Principal) bool {
+	return fp.f(c, p)
+}
+
+// String implements the Policy interface.
+func (fp *funcPolicy) String() string {
+	return fp.name
+}
+
+// Require requires the principal of the route to satisfy all policies.
+// They are checked after the middleware, so an authentication middleware
+// should be one of them.
+func (rt *Route) Require(policies ...Policy) *Route {
+	rt.requires = append(rt.requires, policies...)
+	return rt
+}
+
+// g.Require requires the principal of all the routes in g to satisfy all
+// policies, see Route.Require.
+func (g *GroupRouter) Require(policies ...Policy) {
+	g.r.requires = append(g.r.requires, policies...)
+}
+
+// authorize checks the policies of rt. It returns a 401 HTTPError if the
+// client is not authenticated, and a 403 HTTPError if it's not allowed.
+func (rt *Route) authorize(c *Context) error {
+	if len(rt.r.requires) == 0 && len(rt.requires) == 0 {
+		return nil
+	}
+	p := c.Principal()
+	if p == nil {
+		return NewHTTPError(http.StatusUnauthorized)
+	}
+	for _, policy := range rt.r.requires {
+		if !policy.Allow(c, p) {
+			return NewHTTPError(http.StatusForbidden)
+		}
+	}
+	for _, policy := range rt.requires {
+		if !policy.Allow(c, p) {
+			return NewHTTPError(http.StatusForbidden)
+		}
+	}
+	return nil
+}
+
+// policyNames returns the descriptions of policies.
+func policyNames(policies []Policy) []string {
+	names := make([]string, 0, len(policies))
+	for _, p := range policies {
+		names = append(names, p.String())
+	}
+	return names
+}
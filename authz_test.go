@@ -0,0 +1,104 @@
+package ctx
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy(t *testing.T) {
+	p := &Principal{
+		Roles:       []string{"editor"},
+		Scopes:      []string{"read", "write"},
+		Permissions: []string{"post.delete"},
+	}
+	assert.True(t, Roles("admin", "editor").Allow(nil, p))
+	assert.False(t, Roles("admin").Allow(nil, p))
+	assert.True(t, Scopes("read", "write").Allow(nil, p))
+	assert.False(t, Scopes("read", "admin").Allow(nil, p))
+	assert.True(t, Permissions("post.delete").Allow(nil, p))
+	assert.False(t, Permissions("post.create").Allow(nil, p))
+	assert.Equal(t, "role:admin|editor", Roles("admin", "editor").String())
+	assert.Equal(t, "scope:read,write", Scopes("read", "write").String())
+}
+
+func TestRequire(t *testing.T) {
+	resetRouter()
+	auth := APIKey(&APIKeyConfig{Keys: map[string]*Principal{
+		"admin": {ID: "a", Roles: []string{"admin"}, Scopes: []string{"read"}},
+		"user":  {ID: "u", Scopes: []string{"read"}},
+	}})
+	g := Group("/admin")
+	g.Use(auth)
+	g.Require(Roles("admin"))
+	g.GET("/", h).Require(Scopes("read"))
+	g.Group("/sub").GET("/", h)
+	GET("/optional", h).Require(PolicyFunc("owner", func(c *Context, p *Principal) bool {
+		return p.ID == c.Query("id")
+	}))
+
+	for _, path := range []string{"/admin/", "/admin/sub/"} {
+		res := authRequest(path, map[string]string{"X-API-Key": "admin"})
+		assert.Equal(t, 200, res.Code)
+		res = authRequest(path, map[string]string{"X-API-Key": "user"})
+		assert.Equal(t, http.StatusForbidden, res.Code)
+		res = authRequest(path, nil)
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	}
+	res := authRequest("/optional", nil)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+	routes := Routes()
+	assert.Equal(t, "/admin/", routes[0].Path)
+	assert.Equal(t, []string{"role:admin", "scope:read"}, routes[0].Requires)
+	assert.Equal(t, []string{"role:admin"}, routes[1].Requires)
+	assert.Equal(t, []string{"owner"}, routes[2].Requires)
+}
+
+func TestRequireAfterGroup(t *testing.T) {
+	resetRouter()
+	auth := APIKey(&APIKeyConfig{Keys: map[string]*Principal{
+		"admin": {ID: "a", Roles: []string{"admin"}},
+		"guest": {ID: "g", Roles: []string{"guest"}},
+	}})
+	g := Group("/api")
+	g.Use(auth)
+	// leave spare capacity in the parent's policies
+	for i := 0; i < 3; i++ {
+		g.Require(PolicyFunc("any", func(c *Context, p *Principal) bool {
+			return true
+		}))
+	}
+	child := g.Group("/admin")
+	child.Require(Roles("admin"))
+	child.GET("/", h)
+	// the parent's policies must not overwrite the child's
+	g.Require(Roles("guest", "admin"))
+	g.GET("/", h)
+
+	res := authRequest("/api/admin/", map[string]string{"X-API-Key": "guest"})
+	assert.Equal(t, http.StatusForbidden, res.Code)
+	res = authRequest("/api/admin/", map[string]string{"X-API-Key": "admin"})
+	assert.Equal(t, 200, res.Code)
+	res = authRequest("/api/", map[string]string{"X-API-Key": "guest"})
+	assert.Equal(t, 200, res.Code)
+}
+
+func TestRequireAfterAbort(t *testing.T) {
+	resetRouter()
+	Use(func(c *Context) error {
+		if c.Query("abort") != "" {
+			c.String("aborted")
+			return c.Abort()
+		}
+		return nil
+	})
+	GET("/", h).Require(Roles("admin"))
+
+	res := authRequest("/?abort=1", nil)
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "aborted", res.Body.String())
+	res = authRequest("/", nil)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+}
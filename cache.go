@@ -0,0 +1,467 @@
+package ctx
+
+import (
+	"container/list"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errCacheRequired is the error if the ResponseCache middleware is not used.
+var errCacheRequired = errors.New("ResponseCache middleware is required")
+
+// CachedResponse is a response stored in a CacheStore.
+type CachedResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	// Tags is the tags set by c.CacheTags, for the invalidation.
+	Tags    []string
+	Created time.Time
+	Expires time.Time
+	// Vary is the names of the Vary headers. If it's set, the entry is only
+	// the index of the variants, which are stored at the keys with the values
+	// of the headers, and tagged with VaryTag of the index key. A store
+	// should delete the variants with the index.
+	Vary []string
+	// Public is if the response is public, only the public responses are
+	// served to the requests with the credentials.
+	Public bool
+}
+
+// VaryTag returns the tag of the variants of the index at key, see
+// CachedResponse.Vary.
+func VaryTag(key string) string {
+	return "ctx:vary:" + key
+}
+
+// expired returns if res is expired at now.
+func (res *CachedResponse) expired(now time.Time) bool {
+	return !now.Before(res.Expires)
+}
+
+// CacheStore stores the responses of ResponseCache.
+type CacheStore interface {
+	// Get returns the response of key, nil if not found or expired.
+	Get(key string) (*CachedResponse, error)
+	// Set stores res at key until res.Expires.
+	Set(key string, res *CachedResponse) error
+	// Delete deletes the response of key.
+	Delete(key string) error
+	// DeleteTags deletes the responses with any of tags.
+	DeleteTags(tags ...string) error
+}
+
+// CacheConfig is the config of ResponseCache.
+type CacheConfig struct {
+	// Store is the CacheStore, a LRUCacheStore of 1024 responses if nil.
+	Store CacheStore
+	// TTL is the default time to live of the responses, 1 minute if zero.
+	// The max-age and s-maxage of the response Cache-Control override it.
+	TTL time.Duration
+	// QueryParams is the query params in the cache key, all the query params
+	// if nil.
+	QueryParams []string
+	// CredentialHeaders is the request headers of the credentials besides
+	// Authorization and Cookie, e.g. the Header of APIKey, "X-API-Key" if
+	// nil.
+	CredentialHeaders []string
+}
+
+// ResponseCache caches the complete responses of GET and HEAD requests on
+// the server, keyed by the method, the scheme, the host, the path, the query
+// params and the Vary headers. Only the 200 responses are stored, the responses with
+// Set-Cookie, Vary: *, or the Cache-Control no-store, no-cache or private are
+// not. The responses of the requests with the credentials, i.e. the
+// CredentialHeaders or c.Principal(), are stored and served only if they are
+// public. The request Cache-Control no-store skips the cache, no-cache and
+// max-age refresh the stale responses.
+type ResponseCache struct {
+	conf *CacheConfig
+}
+
+// NewResponseCache returns a ResponseCache with cfg, which can be nil.
+func NewResponseCache(cfg *CacheConfig) *ResponseCache {
+	conf := new(CacheConfig)
+	if cfg != nil {
+		*conf = *cfg
+	}
+	if conf.Store == nil {
+		conf.Store = NewLRUCacheStore(1024)
+	}
+	if conf.TTL <= 0 {
+		conf.TTL = time.Minute
+	}
+	if conf.CredentialHeaders == nil {
+		conf.CredentialHeaders = []string{"X-API-Key"}
+	}
+	conf.CredentialHeaders = append([]string{"Authorization", "Cookie"}, conf.CredentialHeaders...)
+	return &ResponseCache{conf: conf}
+}
+
+// Handler returns the middleware which serves the cached responses and
+// stores the new ones, e.g. ctx.Use(rc.Handler()). It runs for the other
+// methods too, so their handlers can use c.InvalidateCache. Put it after
+// ETag, so the stored response is the one before the ETag check, and after
+// the authentication, so c.Principal() is checked before serving.
+func (rc *ResponseCache) Handler() Handler {
+	return func(c *Context) error {
+		c.cache = rc
+		if c.Req.Method != http.MethodGet && c.Req.Method != http.MethodHead {
+			return nil
+		}
+		reqCC := parseCacheControl(c.Req.Header.Get("Cache-Control"))
+		if _, ok := reqCC["no-store"]; ok {
+			return nil
+		}
+		key := rc.key(c)
+		now := time.Now()
+		if _, ok := reqCC["no-cache"]; !ok {
+			res := rc.lookup(c, key)
+			if res != nil && (res.Public || !rc.credentialed(c)) && fresh(res, reqCC, now) {
+				c.ResHeader().Set("X-Cache", "HIT")
+				return rc.serve(c, res, now)
+			}
+		}
+		c.bufferResponse(func() {
+			rc.store(c, key, now)
+		})
+		return nil
+	}
+}
+
+// InvalidateTags deletes the responses with any of tags.
+func (rc *ResponseCache) InvalidateTags(tags ...string) error {
+	return e("invalidate cache error", rc.conf.Store.DeleteTags(tags...))
+}
+
+// key returns the cache key of the request without the Vary headers, the
+// scheme and the host are in the key since the response may differ by them.
+func (rc *ResponseCache) key(c *Context) string {
+	query := c.queryValues()
+	if rc.conf.QueryParams != nil {
+		selected := make(url.Values)
+		for _, k := range rc.conf.QueryParams {
+			if vs, ok := query[k]; ok {
+				selected[k] = vs
+			}
+		}
+		query = selected
+	}
+	return c.Req.Method + " " + c.Scheme() + "://" + c.RealHost() +
+		c.Req.URL.Path + "?" + query.Encode()
+}
+
+// varyKey returns the key of the variant of the request with the Vary
+// headers names.
+func varyKey(key string, r *http.Request, names []string) string {
+	var b strings.Builder
+	b.WriteString(key)
+	for _, name := range names {
+		b.WriteString("\n")
+		b.WriteString(name)
+		b.WriteString(": ")
+		b.WriteString(strings.Join(r.Header.Values(name), ","))
+	}
+	return b.String()
+}
+
+// credentialed returns if the request has the credentials.
+func (rc *ResponseCache) credentialed(c *Context) bool {
+	if c.Principal() != nil {
+		return true
+	}
+	for _, name := range rc.conf.CredentialHeaders {
+		if c.Req.Header.Get(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the cached response of the request, nil if not found.
+func (rc *ResponseCache) lookup(c *Context, key string) *CachedResponse {
+	res, err := rc.conf.Store.Get(key)
+	if err == nil && res != nil && len(res.Vary) != 0 {
+		res, err = rc.conf.Store.Get(varyKey(key, c.Req, res.Vary))
+	}
+	if err != nil {
+		log.Printf("%s get cache error: %v\n", "[ctx]", err)
+		return nil
+	}
+	return res
+}
+
+// fresh returns if res satisfies the max-age of the request Cache-Control.
+func fresh(res *CachedResponse, reqCC map[string]string, now time.Time) bool {
+	if res.expired(now) {
+		return false
+	}
+	if v, ok := reqCC["max-age"]; ok {
+		age, err := strconv.Atoi(v)
+		return err == nil && now.Sub(res.Created) <= time.Duration(age)*time.Second
+	}
+	return true
+}
+
+// serve responses the current request with res.
+func (rc *ResponseCache) serve(c *Context, res *CachedResponse, now time.Time) error {
+	h := c.ResHeader()
+	for k, v := range res.Header {
+		h[k] = append([]string(nil), v...)
+	}
+	h.Set("Age", strconv.Itoa(int(now.Sub(res.Created)/time.Second)))
+	c.SetStatusCode(res.Status)
+	if _, err := c.Res.Write(res.Body); err != nil {
+		return e("write cached response error", err)
+	}
+	c.done = true
+	return c.Abort()
+}
+
+// store stores the buffered response of the current request at key.
+func (rc *ResponseCache) store(c *Context, key string, now time.Time) {
+	h := c.ResHeader()
+	if c.res.status != http.StatusOK || h.Get("Set-Cookie") != "" {
+		return
+	}
+	h.Set("X-Cache", "MISS")
+	ttl, public, ok := rc.ttl(h.Get("Cache-Control"), rc.credentialed(c))
+	if !ok {
+		return
+	}
+	var names []string
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name == "*" {
+				return
+			} else if name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	header := h.Clone()
+	header.Del("X-Cache")
+	res := &CachedResponse{
+		Status:  c.res.status,
+		Header:  header,
+		Body:    append([]byte(nil), c.res.buf.Bytes()...),
+		Tags:    c.cacheTags,
+		Created: now,
+		Expires: now.Add(ttl),
+		Public:  public,
+	}
+	var err error
+	if len(names) != 0 {
+		sort.Strings(names)
+		index := &CachedResponse{Tags: res.Tags, Created: now, Expires: res.Expires, Vary: names}
+		res.Tags = append(append([]string(nil), res.Tags...), VaryTag(key))
+		if err = rc.conf.Store.Set(key, index); err == nil {
+			err = rc.conf.Store.Set(varyKey(key, c.Req, names), res)
+		}
+	} else {
+		err = rc.conf.Store.Set(key, res)
+	}
+	if err != nil {
+		log.Printf("%s set cache error: %v\n", "[ctx]", err)
+	}
+}
+
+// ttl returns the time to live of the response with the Cache-Control cc and
+// if it's public, ok is false if it's not cacheable.
+func (rc *ResponseCache) ttl(cc string, cred bool) (ttl time.Duration, public, ok bool) {
+	directives := parseCacheControl(cc)
+	for _, d := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := directives[d]; ok {
+			return 0, false, false
+		}
+	}
+	_, public = directives["public"]
+	ttl = rc.conf.TTL
+	for _, d := range []string{"max-age", "s-maxage"} {
+		if v, ok := directives[d]; ok {
+			age, err := strconv.Atoi(v)
+			if err != nil {
+				return 0, false, false
+			}
+			ttl, public = time.Duration(age)*time.Second, public || d == "s-maxage"
+		}
+	}
+	if ttl <= 0 || (cred && !public) {
+		return 0, false, false
+	}
+	return ttl, public, true
+}
+
+// parseCacheControl returns the directives of the Cache-Control header.
+func parseCacheControl(cc string) map[string]string {
+	directives := make(map[string]string)
+	for _, d := range strings.Split(cc, ",") {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(d, "=")
+		directives[strings.ToLower(strings.TrimSpace(k))] = strings.Trim(strings.TrimSpace(v), `"`)
+	}
+	return directives
+}
+
+// CacheTags adds tags to the response of the current request for the
+// invalidation, e.g. c.CacheTags("user:1").
+func (c *Context) CacheTags(tags ...string) {
+	c.cacheTags = append(c.cacheTags, tags...)
+}
+
+// vary adds name to the Vary header of the response if it's not there.
+func (c *Context) vary(name string) {
+	h := c.ResHeader()
+	for _, v := range h.Values("Vary") {
+		for _, n := range strings.Split(v, ",") {
+			if n = strings.TrimSpace(n); n == "*" || strings.EqualFold(n, name) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", name)
+}
+
+// InvalidateCache deletes the cached responses with any of tags, e.g. after
+// the resources are changed. It returns a error if the ResponseCache
+// middleware is not used.
+func (c *Context) InvalidateCache(tags ...string) error {
+	if c.cache == nil {
+		return e("invalidate cache error", errCacheRequired)
+	}
+	return c.cache.InvalidateTags(tags...)
+}
+
+// LRUCacheStore is the in memory CacheStore, which evicts the least
+// recently used responses if it's full.
+type LRUCacheStore struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+	tags  map[string]map[string]struct{}
+}
+
+// lruEntry is an element of LRUCacheStore.ll.
+type lruEntry struct {
+	key string
+	res *CachedResponse
+}
+
+// NewLRUCacheStore returns a LRUCacheStore of at most size responses.
+func NewLRUCacheStore(size int) *LRUCacheStore {
+	if size <= 0 {
+		size = 1
+	}
+	return &LRUCacheStore{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+		tags:  make(map[string]map[string]struct{}),
+	}
+}
+
+// Get implements the CacheStore interface.
+func (s *LRUCacheStore) Get(key string) (*CachedResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.items[key]
+	if !ok {
+		return nil, nil
+	}
+	res := el.Value.(*lruEntry).res
+	if res.expired(time.Now()) {
+		s.evict(el)
+		return nil, nil
+	}
+	s.ll.MoveToFront(el)
+	return res, nil
+}
+
+// Set implements the CacheStore interface.
+func (s *LRUCacheStore) Set(key string, res *CachedResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.items[key]; ok {
+		s.remove(el)
+	}
+	s.items[key] = s.ll.PushFront(&lruEntry{key: key, res: res})
+	for _, tag := range res.Tags {
+		if s.tags[tag] == nil {
+			s.tags[tag] = make(map[string]struct{})
+		}
+		s.tags[tag][key] = struct{}{}
+	}
+	for s.ll.Len() > s.size {
+		s.evict(s.ll.Back())
+	}
+	return nil
+}
+
+// Delete implements the CacheStore interface.
+func (s *LRUCacheStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.items[key]; ok {
+		s.evict(el)
+	}
+	return nil
+}
+
+// DeleteTags implements the CacheStore interface.
+func (s *LRUCacheStore) DeleteTags(tags ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tag := range tags {
+		for key := range s.tags[tag] {
+			if el, ok := s.items[key]; ok {
+				s.evict(el)
+			}
+		}
+	}
+	return nil
+}
+
+// Len returns the number of the stored responses.
+func (s *LRUCacheStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ll.Len()
+}
+
+// evict removes el, and the variants if it's a Vary index.
+func (s *LRUCacheStore) evict(el *list.Element) {
+	entry := el.Value.(*lruEntry)
+	s.remove(el)
+	if len(entry.res.Vary) == 0 {
+		return
+	}
+	for key := range s.tags[VaryTag(entry.key)] {
+		if el, ok := s.items[key]; ok {
+			s.remove(el)
+		}
+	}
+}
+
+// remove removes el and its tags, the variants of a Vary index are kept
+// when it's replaced.
+func (s *LRUCacheStore) remove(el *list.Element) {
+	entry := s.ll.Remove(el).(*lruEntry)
+	delete(s.items, entry.key)
+	for _, tag := range entry.res.Tags {
+		delete(s.tags[tag], entry.key)
+		if len(s.tags[tag]) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package ctx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseCache(t *testing.T) {
+	rc := NewResponseCache(&CacheConfig{QueryParams: []string{"page"}})
+	calls := 0
+	var h Handler = func(c *Context) error {
+		if err := rc.Handler()(c); err != nil || c.abort {
+			return err
+		}
+		switch c.Req.URL.Path {
+		case "/users":
+			if c.Method() == http.MethodPost {
+				return c.InvalidateCache("users")
+			}
+			calls++
+			c.CacheTags("users")
+			c.ResHeader().Set("X-Calls", strconv.Itoa(calls))
+			return c.Json(Map{"page": c.QueryDefault("page", "1")})
+		case "/lang":
+			calls++
+			c.ResHeader().Set("Vary", "Accept-Language")
+			return c.String(c.Req.Header.Get("Accept-Language"))
+		case "/cc":
+			calls++
+			c.ResHeader().Set("Cache-Control", c.Query("cc"))
+			return c.String("cc")
+		case "/me":
+			calls++
+			c.SetPrincipal(&Principal{ID: c.Query("user")})
+			c.ResHeader().Set("Cache-Control", "max-age=60")
+			return c.String(c.Principal().ID)
+		case "/cookie":
+			calls++
+			c.SetCookie(&http.Cookie{Name: "a", Value: "b"})
+			return c.String("cookie")
+		}
+		return NewHTTPError(http.StatusNotFound)
+	}
+	do := func(method, target string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		return res
+	}
+
+	res := do(http.MethodGet, "/users")
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "MISS", res.Header().Get("X-Cache"))
+	assert.JSONEq(t, `{"page":"1"}`, res.Body.String())
+	res = do(http.MethodGet, "/users?utm=x")
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "HIT", res.Header().Get("X-Cache"))
+	assert.Equal(t, "1", res.Header().Get("X-Calls"))
+	assert.Equal(t, "0", res.Header().Get("Age"))
+	assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"page":"1"}`, res.Body.String())
+	assert.Equal(t, 1, calls)
+
+	// the selected query params and the methods are in the key
+	res = do(http.MethodGet, "/users?page=2")
+	assert.Equal(t, "MISS", res.Header().Get("X-Cache"))
+	assert.JSONEq(t, `{"page":"2"}`, res.Body.String())
+	assert.Equal(t, "HIT", do(http.MethodGet, "/users?page=2").Header().Get("X-Cache"))
+	assert.Equal(t, "MISS", do(http.MethodHead, "/users").Header().Get("X-Cache"))
+	assert.Equal(t, 3, calls)
+
+	// the request Cache-Control
+	assert.Empty(t, do(http.MethodGet, "/users", "Cache-Control", "no-store").Header().Get("X-Cache"))
+	assert.Equal(t, 4, calls)
+	assert.Equal(t, "MISS", do(http.MethodGet, "/users", "Cache-Control", "no-cache").Header().Get("X-Cache"))
+	assert.Equal(t, "5", do(http.MethodGet, "/users").Header().Get("X-Calls"))
+	assert.Equal(t, "HIT", do(http.MethodGet, "/users", "Cache-Control", "max-age=10").Header().Get("X-Cache"))
+
+	// the invalidation by tags
+	assert.Equal(t, 200, do(http.MethodPost, "/users").Code)
+	assert.Equal(t, "MISS", do(http.MethodGet, "/users").Header().Get("X-Cache"))
+	assert.Equal(t, "MISS", do(http.MethodGet, "/users?page=2").Header().Get("X-Cache"))
+	assert.Equal(t, 7, calls)
+	assert.NoError(t, rc.InvalidateTags("users"))
+	assert.Equal(t, "MISS", do(http.MethodGet, "/users").Header().Get("X-Cache"))
+
+	// Vary
+	calls = 0
+	assert.Equal(t, "en", do(http.MethodGet, "/lang", "Accept-Language", "en").Body.String())
+	assert.Equal(t, "fr", do(http.MethodGet, "/lang", "Accept-Language", "fr").Body.String())
+	res = do(http.MethodGet, "/lang", "Accept-Language", "en")
+	assert.Equal(t, "HIT", res.Header().Get("X-Cache"))
+	assert.Equal(t, "en", res.Body.String())
+	res = do(http.MethodGet, "/lang", "Accept-Language", "fr")
+	assert.Equal(t, "HIT", res.Header().Get("X-Cache"))
+	assert.Equal(t, "fr", res.Body.String())
+	assert.Equal(t, 2, calls)
+
+	// the response Cache-Control and Set-Cookie
+	for _, cc := range []string{"no-store", "private,+max-age=60", "no-cache", "max-age=0"} {
+		calls = 0
+		do(http.MethodGet, "/cc?cc="+cc)
+		do(http.MethodGet, "/cc?cc="+cc)
+		assert.Equal(t, 2, calls, cc)
+	}
+	calls = 0
+	do(http.MethodGet, "/cookie")
+	assert.NotEmpty(t, do(http.MethodGet, "/cookie").Header().Get("Set-Cookie"))
+	assert.Equal(t, 2, calls)
+
+	// Authorization
+	calls = 0
+	do(http.MethodGet, "/cc?cc=max-age=60", "Authorization", "Bearer x")
+	do(http.MethodGet, "/cc?cc=max-age=60", "Authorization", "Bearer x")
+	assert.Equal(t, 2, calls)
+	do(http.MethodGet, "/cc?cc=public,+max-age=60", "Authorization", "Bearer x")
+	do(http.MethodGet, "/cc?cc=public,+max-age=60", "Authorization", "Bearer x")
+	assert.Equal(t, 3, calls)
+
+	// the other credentials
+	calls = 0
+	for _, header := range []string{"cookie", "x-api-key"} {
+		target := "http://" + header + ".com/cc?cc="
+		do(http.MethodGet, target+"max-age=60", header, "x")
+		assert.Equal(t, "MISS", do(http.MethodGet, target+"max-age=60").Header().Get("X-Cache"), header)
+		assert.Equal(t, "MISS", do(http.MethodGet, target+"max-age=60", header, "x").Header().Get("X-Cache"), header)
+		do(http.MethodGet, target+"public,+max-age=60", header, "x")
+		assert.Equal(t, "HIT", do(http.MethodGet, target+"max-age=60", header, "x").Header().Get("X-Cache"), header)
+	}
+	assert.Equal(t, 8, calls)
+	assert.Equal(t, "a", do(http.MethodGet, "/me?user=a").Body.String())
+	assert.Equal(t, "b", do(http.MethodGet, "/me?user=b").Body.String())
+
+	// the host is in the key
+	calls = 0
+	do(http.MethodGet, "http://a.com/cc?cc=max-age=60")
+	assert.Equal(t, "MISS", do(http.MethodGet, "http://b.com/cc?cc=max-age=60").Header().Get("X-Cache"))
+	assert.Equal(t, "HIT", do(http.MethodGet, "http://a.com/cc?cc=max-age=60").Header().Get("X-Cache"))
+	assert.Equal(t, 2, calls)
+
+	// errors are not cached
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/none").Code)
+	assert.Empty(t, do(http.MethodGet, "/none").Header().Get("X-Cache"))
+
+	c := NewRequestContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Error(t, c.InvalidateCache("users"))
+}
+
+func TestCacheTTL(t *testing.T) {
+	rc := NewResponseCache(nil)
+	h := Handler(func(c *Context) error {
+		if err := rc.Handler()(c); err != nil || c.abort {
+			return err
+		}
+		c.ResHeader().Set("Cache-Control", "s-maxage=1")
+		return c.String("ok")
+	})
+	get := func() string {
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+		return res.Header().Get("X-Cache")
+	}
+	assert.Equal(t, "MISS", get())
+	assert.Equal(t, "HIT", get())
+	res, err := rc.conf.Store.Get("GET http://example.com/?")
+	assert.NoError(t, err)
+	assert.WithinDuration(t, res.Created.Add(time.Second), res.Expires, 0)
+	res.Expires = time.Now()
+	assert.Equal(t, "MISS", get())
+}
+
+func TestLRUCacheStore(t *testing.T) {
+	s := NewLRUCacheStore(2)
+	expires := time.Now().Add(time.Minute)
+	assert.NoError(t, s.Set("a", &CachedResponse{Body: []byte("a"), Tags: []string{"x"}, Expires: expires}))
+	assert.NoError(t, s.Set("b", &CachedResponse{Body: []byte("b"), Tags: []string{"x", "y"}, Expires: expires}))
+	res, err := s.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "a", string(res.Body))
+
+	// b is the least recently used
+	assert.NoError(t, s.Set("c", &CachedResponse{Body: []byte("c"), Tags: []string{"y"}, Expires: expires}))
+	assert.Equal(t, 2, s.Len())
+	res, _ = s.Get("b")
+	assert.Nil(t, res)
+
+	assert.NoError(t, s.DeleteTags("y"))
+	res, _ = s.Get("c")
+	assert.Nil(t, res)
+	res, _ = s.Get("a")
+	assert.NotNil(t, res)
+	assert.NoError(t, s.DeleteTags("x"))
+	assert.Equal(t, 0, s.Len())
+	assert.Empty(t, s.tags)
+
+	assert.NoError(t, s.Set("d", &CachedResponse{Expires: time.Now()}))
+	res, _ = s.Get("d")
+	assert.Nil(t, res)
+	assert.Equal(t, 0, s.Len())
+	assert.NoError(t, s.Set("e", &CachedResponse{Expires: expires}))
+	assert.NoError(t, s.Delete("e"))
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestLRUCacheStoreVary(t *testing.T) {
+	s := NewLRUCacheStore(4)
+	expires := time.Now().Add(time.Minute)
+	index := &CachedResponse{Vary: []string{"Accept-Language"}, Expires: expires}
+	variant := func(body string) *CachedResponse {
+		return &CachedResponse{Body: []byte(body), Tags: []string{VaryTag("k")}, Expires: expires}
+	}
+	assert.NoError(t, s.Set("k", index))
+	assert.NoError(t, s.Set("k\nen", variant("en")))
+	// replacing the index keeps the variants
+	assert.NoError(t, s.Set("k", index))
+	assert.NoError(t, s.Set("k\nfr", variant("fr")))
+	assert.Equal(t, 3, s.Len())
+
+	// the variants are evicted with the index, the least recently used
+	s.Get("k\nen")
+	s.Get("k\nfr")
+	assert.NoError(t, s.Set("a", &CachedResponse{Expires: expires}))
+	assert.NoError(t, s.Set("b", &CachedResponse{Expires: expires}))
+	assert.Equal(t, 2, s.Len())
+	res, _ := s.Get("k\nfr")
+	assert.Nil(t, res)
+	assert.Empty(t, s.tags)
+}
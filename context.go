@@ -4,12 +4,14 @@ import (
 	"encoding/json"
 	"io"
 	"io/ioutil"
+	"mime"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 )
@@ -23,15 +25,31 @@ type Context struct {
 	Req        *http.Request
 	urlValue   url.Values
 	formValue  url.Values
+	formErr    error
 	StatusCode int
 	done       bool
 	m          Map
-	params     map[string]string
+	params     httprouter.Params
 	abort      bool
 
-	mu *sync.Mutex
+	mu  *sync.Mutex
+	res *response
 
 	routerParamsParsed bool
+	route              *Route
+
+	// err and recovered are the error and the panic of the handler chain,
+	// passed to ErrorHandler and PanicHandler.
+	err       error
+	recovered interface{}
+
+	csrfSecret    []byte
+	principal     *Principal
+	cspNonce      string
+	session       *Session
+	sessionConfig *SessionConfig
+	cache         *ResponseCache
+	cacheTags     []string
 }
 
 func init() {
@@ -52,31 +70,51 @@ func getContext(w http.ResponseWriter, r *http.Request) *Context {
 	return c
 }
 
+// NewRequestContext returns a Context of w and r, which is not from the pool.
+// It's for testing a Handler without the router.
+func NewRequestContext(w http.ResponseWriter, r *http.Request) *Context {
+	c := NewContext()
+	c.reset(w, r)
+	return c
+}
+
 // NewContext returns a empty context
 func NewContext() *Context {
 	return &Context{
 		urlValue:  nil,
 		formValue: nil,
 		m:         make(Map),
-		params:    make(map[string]string),
 		mu:        new(sync.Mutex),
+		res:       new(response),
 	}
 }
 
 // reset resets the context
 func (c *Context) reset(w http.ResponseWriter, r *http.Request) {
-	c.Res = w
+	c.res.reset(w)
+	c.Res = c.res
 	c.Req = r
 
 	c.m = make(Map)
-	c.params = make(map[string]string)
+	c.params = nil
 
 	c.abort = false
 	c.urlValue = nil
 	c.formValue = nil
+	c.formErr = nil
 	c.StatusCode = 0
 	c.done = false
 	c.routerParamsParsed = false
+	c.route = nil
+	c.err = nil
+	c.recovered = nil
+	c.csrfSecret = nil
+	c.principal = nil
+	c.cspNonce = ""
+	c.session = nil
+	c.sessionConfig = nil
+	c.cache = nil
+	c.cacheTags = nil
 }
 
 // Set set a couple of k v to a custom map.
@@ -114,17 +152,24 @@ func (c *Context) Ensure(f func()) {
 
 // Request Method
 
-// Params get the router param with the specific k.
-func (c *Context) Params(k string) string {
+// Route returns the matched route of the current request, nil if no route
+// matched.
+func (c *Context) Route() *Route {
+	return c.route
+}
+
+// routerParams returns the router params of the current request.
+func (c *Context) routerParams() httprouter.Params {
 	if !c.routerParamsParsed {
-		ctx := c.Req.Context()
-		ps := httprouter.ParamsFromContext(ctx)
-		for _, p := range ps {
-			c.params[p.Key] = p.Value
-		}
+		c.params = httprouter.ParamsFromContext(c.Req.Context())
 		c.routerParamsParsed = true
 	}
-	return c.params[k]
+	return c.params
+}
+
+// Params get the router param with the specific k.
+func (c *Context) Params(k string) string {
+	return c.routerParams().ByName(k)
 }
 
 // ReqHeader return the request header.
@@ -145,37 +190,48 @@ func (c *Context) ReqBodyByte() ([]byte, error) {
 
 // Exists returns if the k exists in query string or form value.
 func (c *Context) Exists(k string) bool {
-	c.Query("")
-	formValue := map[string][]string(c.formValue)
-	urlValue := map[string][]string(c.urlValue)
-	if len(formValue[k]) != 0 {
-		return true
-	}
-	if len(urlValue[k]) != 0 {
-		return true
-	}
-	return false
+	return len(c.queryValues()[k]) != 0 || len(c.formValues()[k]) != 0
 }
 
-// Query returns a string value of k. If the specific k not exists, returns "".
-func (c *Context) Query(k string) string {
-	if c.Method() == "GET" {
-		if c.urlValue == nil {
-			c.urlValue = c.Req.URL.Query()
-		}
-		return c.urlValue.Get(k)
-	} else {
-		if c.formValue == nil || c.urlValue == nil {
-			c.Req.ParseForm()
-			c.formValue = c.Req.Form
-			c.urlValue = c.Req.URL.Query()
+// queryValues returns the parsed URL query.
+func (c *Context) queryValues() url.Values {
+	if c.urlValue == nil {
+		c.urlValue = c.Req.URL.Query()
+	}
+	return c.urlValue
+}
+
+// formValues returns the parsed form body, both url encoded and multipart,
+// it's empty if the request has no form body. The multipart body is parsed
+// with the limits of DefaultMultipartConfig.
+func (c *Context) formValues() url.Values {
+	if c.formValue == nil {
+		ctype, _, _ := mime.ParseMediaType(c.Req.Header.Get("Content-Type"))
+		if ctype == "multipart/form-data" && c.Req.MultipartForm == nil {
+			c.limitBody(DefaultMultipartConfig)
+			c.formErr = c.Req.ParseMultipartForm(DefaultMultipartConfig.MaxMemory)
+		} else {
+			c.formErr = c.Req.ParseForm()
 		}
-		if v := c.formValue.Get(k); v != "" {
-			return v
+		c.formValue = c.Req.PostForm
+		if c.formValue == nil {
+			c.formValue = make(url.Values)
 		}
-		return c.urlValue.Get(k)
 	}
-	return ""
+	return c.formValue
+}
+
+// Query returns a string value of k in the URL query. If the specific k not
+// exists, returns "". The form body is not read, use Form for it.
+func (c *Context) Query(k string) string {
+	return c.queryValues().Get(k)
+}
+
+// Form returns a string value of k in the form body of POST, PUT and PATCH,
+// either application/x-www-form-urlencoded or multipart/form-data. If the
+// specific k not exists, returns "".
+func (c *Context) Form(k string) string {
+	return c.formValues().Get(k)
 }
 
 // QueryInt returns a int value and error if atoi wrong.
@@ -211,13 +267,26 @@ func (c *Context) SetCookie(cookie *http.Cookie) {
 	http.SetCookie(c.Res, cookie)
 }
 
-// File returns the formfile with the specific key.
+// File returns the first formfile with the specific key, within the limits
+// of DefaultMultipartConfig, see c.Files. It returns http.ErrMissingFile if
+// there is no file.
 func (c *Context) File(key string) (
 	multipart.File,
 	*multipart.FileHeader,
 	error,
 ) {
-	return c.Req.FormFile(key)
+	files, err := c.Files(key, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(files) == 0 {
+		return nil, nil, http.ErrMissingFile
+	}
+	f, err := files[0].Open()
+	if err != nil {
+		return nil, nil, e("open multipart file error", err)
+	}
+	return f, files[0], nil
 }
 
 // RemoteAddr returns RemoteAddr of the current request.
@@ -309,6 +378,17 @@ func (c *Context) ServeFile(filepath string) error {
 	return nil
 }
 
+// ServeContent response the current request with the content, e.g. a
+// generated file. It handles the Range, If-Modified-Since and If-Range
+// headers, and the Content-Type is detected from the extension of name or
+// the content if it's not set. Set the ETag header before it if you have
+// one, so the If-Match and If-None-Match are handled too.
+func (c *Context) ServeContent(name string, modtime time.Time, content io.ReadSeeker) error {
+	http.ServeContent(c.Res, c.Req, name, modtime, content)
+	c.done = true
+	return nil
+}
+
 // Success response the current request with the specific format of data. The
 // type is json, and you can change format by setting ctx.SuccessJson.
 // NOTE: implement your own SuccessCB before use *Context.Success
@@ -324,6 +404,12 @@ func (c *Context) Error(code int, msg interface{}) error {
 	return ErrorCB(c, code, msg)
 }
 
+// Flush sends the buffered response data to the client, it works with both
+// HTTP/1.1 and HTTP/2 responses.
+func (c *Context) Flush() error {
+	return e("flush error", http.NewResponseController(c.Res).Flush())
+}
+
 // Write response the current request with data in its body.
 func (c *Context) Write(data []byte) error {
 	if ct := c.Res.Header().Get("Content-Type"); ct == "" {
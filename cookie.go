@@ -0,0 +1,232 @@
+package ctx
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCookie is the error of a cookie which is tampered or signed by
+// an unknown key.
+var ErrInvalidCookie = errors.New("invalid cookie")
+
+// ErrCookieExpired is the error of a cookie which is expired.
+var ErrCookieExpired = errors.New("cookie expired")
+
+// CookieKeyring is the Keyring used by the signed and encrypted cookies of
+// Context.
+// NOTE: set it before use *Context.SetSignedCookie and the others.
+var CookieKeyring *Keyring
+
+// AllowInsecureCookies stops the signed and encrypted cookies of Context
+// from being Secure, so they work over plain http in development.
+var AllowInsecureCookies = false
+
+// Keyring is the keys to sign and encrypt cookies. The first key signs and
+// encrypts, all the keys verify and decrypt, so the cookies of the old keys
+// are still valid during rotation. Put the new key first to rotate.
+type Keyring struct {
+	signKeys [][]byte
+	aeads    []cipher.AEAD
+}
+
+// NewKeyring returns a Keyring of keys, a key can be any length but should
+// have at least 32 random bytes. It panics if keys is empty.
+func NewKeyring(keys ...[]byte) *Keyring {
+	if len(keys) == 0 {
+		panic("[ctx] no cookie key")
+	}
+	kr := &Keyring{}
+	for _, k := range keys {
+		kr.signKeys = append(kr.signKeys, deriveKey(k, "sign"))
+		// a 32 bytes key always makes a valid AES-256 cipher
+		block, _ := aes.NewCipher(deriveKey(k, "encrypt"))
+		aead, _ := cipher.NewGCM(block)
+		kr.aeads = append(kr.aeads, aead)
+	}
+	return kr
+}
+
+// deriveKey derives a 32 bytes key for purpose from key.
+func deriveKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("ctx cookie " + purpose))
+	return mac.Sum(nil)
+}
+
+// Sign returns value with the HMAC-SHA256 signature, as the value of the
+// cookie `name`. A zero expires means never expire.
+func (kr *Keyring) Sign(name, value string, expires time.Time) string {
+	v := base64.RawURLEncoding.EncodeToString(withExpires(value, expires))
+	return v + "." + base64.RawURLEncoding.EncodeToString(
+		cookieMAC(kr.signKeys[0], name, v),
+	)
+}
+
+// Verify returns the value signed by Sign.
+func (kr *Keyring) Verify(name, v string) (string, error) {
+	i := strings.LastIndexByte(v, '.')
+	if i < 0 {
+		return "", ErrInvalidCookie
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(v[i+1:])
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	for _, k := range kr.signKeys {
+		if hmac.Equal(mac, cookieMAC(k, name, v[:i])) {
+			b, err := base64.RawURLEncoding.DecodeString(v[:i])
+			if err != nil {
+				return "", ErrInvalidCookie
+			}
+			return checkExpires(b)
+		}
+	}
+	return "", ErrInvalidCookie
+}
+
+// Encrypt returns value encrypted by AES-GCM, as the value of the cookie
+// `name`. A zero expires means never expire.
+func (kr *Keyring) Encrypt(name, value string, expires time.Time) (string, error) {
+	aead := kr.aeads[0]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(
+		aead.Seal(nonce, nonce, withExpires(value, expires), []byte(name)),
+	), nil
+}
+
+// Decrypt returns the value encrypted by Encrypt.
+func (kr *Keyring) Decrypt(name, v string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	for _, aead := range kr.aeads {
+		if len(b) < aead.NonceSize() {
+			break
+		}
+		nonce, data := b[:aead.NonceSize()], b[aead.NonceSize():]
+		if p, err := aead.Open(nil, nonce, data, []byte(name)); err == nil {
+			return checkExpires(p)
+		}
+	}
+	return "", ErrInvalidCookie
+}
+
+// cookieMAC returns the HMAC-SHA256 of the cookie `name` with value v.
+func cookieMAC(key []byte, name, v string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name + "|" + v))
+	return mac.Sum(nil)
+}
+
+// withExpires prepends the unix time of expires to value.
+func withExpires(value string, expires time.Time) []byte {
+	b := make([]byte, 8, 8+len(value))
+	if !expires.IsZero() {
+		binary.BigEndian.PutUint64(b, uint64(expires.Unix()))
+	}
+	return append(b, value...)
+}
+
+// checkExpires returns the value of b made by withExpires, or
+// ErrCookieExpired.
+func checkExpires(b []byte) (string, error) {
+	if len(b) < 8 {
+		return "", ErrInvalidCookie
+	}
+	exp := int64(binary.BigEndian.Uint64(b))
+	if exp != 0 && time.Now().Unix() >= exp {
+		return "", ErrCookieExpired
+	}
+	return string(b[8:]), nil
+}
+
+// cookieExpires returns the expiry of cookie, zero if it's a session cookie.
+func cookieExpires(cookie *http.Cookie) time.Time {
+	if cookie.MaxAge > 0 {
+		return time.Now().Add(time.Duration(cookie.MaxAge) * time.Second)
+	}
+	return cookie.Expires
+}
+
+// secureCookie sets the secure defaults of cookie: HttpOnly, Secure unless
+// AllowInsecureCookies, and SameSite=Lax if SameSite is not set.
+func secureCookie(cookie *http.Cookie) {
+	cookie.HttpOnly = true
+	cookie.Secure = !AllowInsecureCookies
+	if cookie.SameSite == 0 {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+}
+
+// SetSignedCookie sets cookie in response with its value signed by
+// CookieKeyring. The cookie is readable but can not be modified by clients.
+// The expiry of the cookie is signed too. See secure defaults in
+// AllowInsecureCookies.
+func (c *Context) SetSignedCookie(cookie *http.Cookie) error {
+	if CookieKeyring == nil {
+		return e("set signed cookie error", errors.New("nil CookieKeyring"))
+	}
+	cp := *cookie
+	cp.Value = CookieKeyring.Sign(cp.Name, cp.Value, cookieExpires(&cp))
+	secureCookie(&cp)
+	c.SetCookie(&cp)
+	return nil
+}
+
+// SignedCookie returns the value of the cookie set by SetSignedCookie. It
+// returns an error if the cookie not exists, is invalid or expired.
+func (c *Context) SignedCookie(name string) (string, error) {
+	if CookieKeyring == nil {
+		return "", e("signed cookie error", errors.New("nil CookieKeyring"))
+	}
+	cookie, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return CookieKeyring.Verify(name, cookie.Value)
+}
+
+// SetEncryptedCookie sets cookie in response with its value encrypted by
+// CookieKeyring. The cookie can not be read or modified by clients. The
+// expiry of the cookie is encrypted too. See secure defaults in
+// AllowInsecureCookies.
+func (c *Context) SetEncryptedCookie(cookie *http.Cookie) error {
+	if CookieKeyring == nil {
+		return e("set encrypted cookie error", errors.New("nil CookieKeyring"))
+	}
+	cp := *cookie
+	v, err := CookieKeyring.Encrypt(cp.Name, cp.Value, cookieExpires(&cp))
+	if err != nil {
+		return e("set encrypted cookie error", err)
+	}
+	cp.Value = v
+	secureCookie(&cp)
+	c.SetCookie(&cp)
+	return nil
+}
+
+// EncryptedCookie returns the value of the cookie set by SetEncryptedCookie.
+// It returns an error if the cookie not exists, is invalid or expired.
+func (c *Context) EncryptedCookie(name string) (string, error) {
+	if CookieKeyring == nil {
+		return "", e("encrypted cookie error", errors.New("nil CookieKeyring"))
+	}
+	cookie, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return CookieKeyring.Decrypt(name, cookie.Value)
+}
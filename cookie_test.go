@@ -0,0 +1,83 @@
+package ctx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyringRotation(t *testing.T) {
+	old := NewKeyring([]byte("old"))
+	kr := NewKeyring([]byte("new"), []byte("old"))
+	v := old.Sign("a", "data", time.Time{})
+	got, err := kr.Verify("a", v)
+	assert.NoError(t, err)
+	assert.Equal(t, "data", got)
+	_, err = kr.Verify("b", v)
+	assert.Equal(t, ErrInvalidCookie, err)
+	_, err = NewKeyring([]byte("other")).Verify("a", v)
+	assert.Equal(t, ErrInvalidCookie, err)
+
+	v, err = old.Encrypt("a", "data", time.Time{})
+	assert.NoError(t, err)
+	assert.NotContains(t, v, "data")
+	got, err = kr.Decrypt("a", v)
+	assert.NoError(t, err)
+	assert.Equal(t, "data", got)
+	_, err = kr.Decrypt("b", v)
+	assert.Equal(t, ErrInvalidCookie, err)
+}
+
+func TestKeyringExpires(t *testing.T) {
+	kr := NewKeyring([]byte("key"))
+	_, err := kr.Verify("a", kr.Sign("a", "data", time.Now().Add(-time.Second)))
+	assert.Equal(t, ErrCookieExpired, err)
+	v, _ := kr.Encrypt("a", "data", time.Now().Add(-time.Second))
+	_, err = kr.Decrypt("a", v)
+	assert.Equal(t, ErrCookieExpired, err)
+	v, _ = kr.Encrypt("a", "data", time.Now().Add(time.Hour))
+	_, err = kr.Decrypt("a", v)
+	assert.NoError(t, err)
+}
+
+func TestSignedEncryptedCookie(t *testing.T) {
+	c := getContext(nil, nil)
+	assert.Error(t, c.SetSignedCookie(&http.Cookie{Name: "a"}))
+
+	CookieKeyring = NewKeyring([]byte("key"))
+	defer func() {
+		CookieKeyring = nil
+	}()
+	res := httptest.NewRecorder()
+	c = getContext(res, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.NoError(t, c.SetSignedCookie(&http.Cookie{
+		Name: "s", Value: "signed", MaxAge: 60,
+	}))
+	assert.NoError(t, c.SetEncryptedCookie(&http.Cookie{
+		Name: "e", Value: "encrypted",
+	}))
+	cookies := res.Result().Cookies()
+	assert.Len(t, cookies, 2)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.True(t, cookies[0].Secure)
+	assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	c = getContext(httptest.NewRecorder(), req)
+	v, err := c.SignedCookie("s")
+	assert.NoError(t, err)
+	assert.Equal(t, "signed", v)
+	v, err = c.EncryptedCookie("e")
+	assert.NoError(t, err)
+	assert.Equal(t, "encrypted", v)
+	_, err = c.SignedCookie("e")
+	assert.Error(t, err)
+	_, err = c.EncryptedCookie("none")
+	assert.Error(t, err)
+}
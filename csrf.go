@@ -0,0 +1,195 @@
+package ctx
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// csrfSecretLen is the length of the CSRF secret.
+const csrfSecretLen = 32
+
+// csrfSessionKey is the session key of the CSRF secret.
+const csrfSessionKey = "_csrf"
+
+// CSRFConfig is the config of CSRF.
+type CSRFConfig struct {
+	// Session stores the secret in the session (the synchronizer token
+	// pattern), the Sessions middleware must run before CSRF. Otherwise the
+	// secret is stored in a cookie (the double submit cookie pattern).
+	Session bool
+	// CookieName is the name of the CSRF cookie, default is "ctx_csrf".
+	CookieName string
+	// CookiePath is the path of the CSRF cookie, default is "/".
+	CookiePath string
+	Domain     string
+	Secure     bool
+	// Header is the request header of the token, default is "X-CSRF-Token".
+	Header string
+	// FormField is the form field of the token, default is "csrf_token".
+	FormField string
+	// TrustedOrigins is the hosts allowed in Origin and Referer besides the
+	// host of the request, e.g. "www.example.com".
+	TrustedOrigins []string
+	// Exempt skips the check of the requests it returns true. Use
+	// Route.SkipCSRF to skip a route.
+	Exempt func(c *Context) bool
+}
+
+// SkipCSRF skips the CSRF check of the route.
+func (rt *Route) SkipCSRF() *Route {
+	rt.skipCSRF = true
+	return rt
+}
+
+// CSRF returns the middleware which checks the CSRF token of the unsafe
+// requests, the methods except GET, HEAD, OPTIONS and TRACE. The token is
+// from c.CSRFToken() and sent back in the header or the form field. Origin
+// and Referer are checked too. It returns a 403 HTTPError if the check fails.
+func CSRF(cfg *CSRFConfig) Handler {
+	conf := CSRFConfig{}
+	if cfg != nil {
+		conf = *cfg
+	}
+	if conf.CookieName == "" {
+		conf.CookieName = "ctx_csrf"
+	}
+	if conf.CookiePath == "" {
+		conf.CookiePath = "/"
+	}
+	if conf.Header == "" {
+		conf.Header = "X-CSRF-Token"
+	}
+	if conf.FormField == "" {
+		conf.FormField = "csrf_token"
+	}
+	return func(c *Context) error {
+		secret, err := conf.secret(c)
+		if err != nil {
+			return err
+		}
+		c.csrfSecret = secret
+		switch c.Method() {
+		case "GET", "HEAD", "OPTIONS", "TRACE":
+			return nil
+		}
+		if (c.route != nil && c.route.skipCSRF) ||
+			(conf.Exempt != nil && conf.Exempt(c)) {
+			return nil
+		}
+		if !conf.checkOrigin(c) {
+			return NewHTTPError(http.StatusForbidden, "CSRF origin mismatch")
+		}
+		token := c.ReqHeader().Get(conf.Header)
+		if token == "" {
+			// c.Form limits the body like c.Files
+			if token = c.Form(conf.FormField); token == "" && c.formErr != nil {
+				return formError(c.formErr)
+			}
+		}
+		if !csrfTokenValid(token, secret) {
+			return NewHTTPError(http.StatusForbidden, "invalid CSRF token")
+		}
+		return nil
+	}
+}
+
+// secret returns the CSRF secret of the client, it creates one if not
+// exists.
+func (conf *CSRFConfig) secret(c *Context) ([]byte, error) {
+	if conf.Session {
+		s := c.Session()
+		if s == nil {
+			return nil, e("csrf error", errSessionRequired)
+		}
+		if b, ok := s.Values[csrfSessionKey].([]byte); ok &&
+			len(b) == csrfSecretLen {
+			return b, nil
+		}
+		b := newCSRFSecret()
+		s.Set(csrfSessionKey, b)
+		return b, nil
+	}
+	if cookie, err := c.Cookie(conf.CookieName); err == nil {
+		b, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+		if err == nil && len(b) == csrfSecretLen {
+			return b, nil
+		}
+	}
+	b := newCSRFSecret()
+	c.SetCookie(&http.Cookie{
+		Name:     conf.CookieName,
+		Value:    base64.RawURLEncoding.EncodeToString(b),
+		Path:     conf.CookiePath,
+		Domain:   conf.Domain,
+		Secure:   conf.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return b, nil
+}
+
+// checkOrigin returns if the Origin, or the Referer if no Origin, is the
+// host of the request or a trusted origin. A HTTPS request must have one of
+// them.
+func (conf *CSRFConfig) checkOrigin(c *Context) bool {
+	origin := c.ReqHeader().Get("Origin")
+	if origin == "" || origin == "null" {
+		origin = c.ReqHeader().Get("Referer")
+	}
+	if origin == "" {
+		return c.Scheme() != "https"
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, c.RealHost()) {
+		return true
+	}
+	for _, o := range conf.TrustedOrigins {
+		if strings.EqualFold(u.Host, o) {
+			return true
+		}
+	}
+	return false
+}
+
+// CSRFToken returns the CSRF token for templates and clients to send back,
+// "" if the CSRF middleware is not used. The token is masked by a random
+// pad each time to prevent BREACH attacks. The response varies by Cookie,
+// where the secret is.
+func (c *Context) CSRFToken() string {
+	if c.csrfSecret == nil {
+		return ""
+	}
+	c.vary("Cookie")
+	token := make([]byte, 2*csrfSecretLen)
+	rand.Read(token[:csrfSecretLen])
+	for i, b := range c.csrfSecret {
+		token[csrfSecretLen+i] = b ^ token[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
+// newCSRFSecret returns a random CSRF secret.
+func newCSRFSecret() []byte {
+	b := make([]byte, csrfSecretLen)
+	rand.Read(b)
+	return b
+}
+
+// csrfTokenValid returns if token is made by CSRFToken with secret.
+func csrfTokenValid(token string, secret []byte) bool {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(b) != 2*csrfSecretLen {
+		return false
+	}
+	for i := 0; i < csrfSecretLen; i++ {
+		b[csrfSecretLen+i] ^= b[i]
+	}
+	return subtle.ConstantTimeCompare(b[csrfSecretLen:], secret) == 1
+}
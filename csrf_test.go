@@ -0,0 +1,158 @@
+package ctx
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func csrfRequest(
+	method, target, token string,
+	cookies []*http.Cookie,
+	header map[string]string,
+) *httptest.ResponseRecorder {
+	var req *http.Request
+	if method == http.MethodPost && token != "" {
+		form := url.Values{"csrf_token": {token}}
+		req = httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req = httptest.NewRequest(method, target, nil)
+		if token != "" {
+			req.Header.Set("X-CSRF-Token", token)
+		}
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	res := httptest.NewRecorder()
+	routerIns.r.ServeHTTP(res, req)
+	return res
+}
+
+func testCSRF(t *testing.T) {
+	GET("/form", func(c *Context) error {
+		return c.String(c.CSRFToken())
+	})
+	POST("/submit", func(c *Context) error {
+		return c.String("ok")
+	})
+	PUT("/submit", func(c *Context) error {
+		return c.String("ok")
+	})
+	POST("/hook", h).SkipCSRF()
+
+	res := csrfRequest(http.MethodGet, "/form", "", nil, nil)
+	token, cookies := res.Body.String(), res.Result().Cookies()
+	assert.NotEmpty(t, token)
+	assert.Len(t, cookies, 1)
+
+	res = csrfRequest(http.MethodPost, "/submit", token, cookies, nil)
+	assert.Equal(t, 200, res.Code)
+	res = csrfRequest(http.MethodPut, "/submit", token, cookies, nil)
+	assert.Equal(t, 200, res.Code)
+	res = csrfRequest(http.MethodPost, "/submit", "", cookies, nil)
+	assert.Equal(t, 403, res.Code)
+	res = csrfRequest(http.MethodPost, "/submit", token, nil, nil)
+	assert.Equal(t, 403, res.Code)
+	res = csrfRequest(http.MethodPost, "/submit", token, cookies, map[string]string{
+		"Origin": "http://evil.com",
+	})
+	assert.Equal(t, 403, res.Code)
+	res = csrfRequest(http.MethodPost, "/submit", token, cookies, map[string]string{
+		"Referer": "http://example.com/form",
+	})
+	assert.Equal(t, 200, res.Code)
+	res = csrfRequest(http.MethodPost, "/submit", token, cookies, map[string]string{
+		"Origin": "https://trusted.com",
+	})
+	assert.Equal(t, 200, res.Code)
+	res = csrfRequest(http.MethodPost, "/hook", "", nil, nil)
+	assert.Equal(t, 200, res.Code)
+}
+
+func TestCSRF(t *testing.T) {
+	resetRouter()
+	Use(CSRF(&CSRFConfig{TrustedOrigins: []string{"trusted.com"}}))
+	testCSRF(t)
+
+	resetRouter()
+	Use(Sessions(&SessionConfig{Store: NewMemoryStore(time.Hour)}))
+	Use(CSRF(&CSRFConfig{Session: true, TrustedOrigins: []string{"trusted.com"}}))
+	testCSRF(t)
+
+	resetRouter()
+	Use(CSRF(&CSRFConfig{Session: true}))
+	GET("/form", h)
+	res := csrfRequest(http.MethodGet, "/form", "", nil, nil)
+	assert.Equal(t, 500, res.Code)
+}
+
+func TestCSRFToken(t *testing.T) {
+	secret := newCSRFSecret()
+	c := NewRequestContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Empty(t, c.CSRFToken())
+	c.csrfSecret = secret
+	t1, t2 := c.CSRFToken(), c.CSRFToken()
+	assert.NotEqual(t, t1, t2)
+	assert.Equal(t, []string{"Cookie"}, c.ResHeader().Values("Vary"))
+	assert.True(t, csrfTokenValid(t1, secret))
+	assert.True(t, csrfTokenValid(t2, secret))
+	assert.False(t, csrfTokenValid(t1, newCSRFSecret()))
+	assert.False(t, csrfTokenValid("x", secret))
+}
+
+func TestCSRFMultipart(t *testing.T) {
+	resetRouter()
+	Use(CSRF(nil))
+	GET("/form", func(c *Context) error {
+		return c.String(c.CSRFToken())
+	})
+	POST("/upload", func(c *Context) error {
+		files, err := c.Files("file", nil)
+		if err != nil {
+			return err
+		}
+		return c.String(strconv.FormatInt(files[0].Size, 10))
+	})
+	res := csrfRequest(http.MethodGet, "/form", "", nil, nil)
+	token, cookies := res.Body.String(), res.Result().Cookies()
+
+	defer func(conf MultipartConfig) {
+		*DefaultMultipartConfig = conf
+	}(*DefaultMultipartConfig)
+	DefaultMultipartConfig.MaxTotalSize = 1000
+	upload := func(size int) *httptest.ResponseRecorder {
+		body := new(bytes.Buffer)
+		w := multipart.NewWriter(body)
+		w.WriteField("csrf_token", token)
+		fw, _ := w.CreateFormFile("file", "a.txt")
+		fw.Write(bytes.Repeat([]byte("a"), size))
+		w.Close()
+		req := httptest.NewRequest(http.MethodPost, "/upload", body)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		res := httptest.NewRecorder()
+		routerIns.r.ServeHTTP(res, req)
+		return res
+	}
+	res = upload(10)
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "10", res.Body.String())
+	// the token in the body doesn't skip the limits
+	res = upload(5000)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, res.Code)
+}
@@ -20,6 +20,27 @@ var ErrNotFound = errors.New("404 Not Found")
 // ErrMethodNotAllow is the MethodNotAllowed error.
 var ErrMethodNotAllow = errors.New("405 Method Not Allow")
 
+// HTTPError is an error with the http status code to response.
+type HTTPError struct {
+	Code int
+	Msg  string
+}
+
+// NewHTTPError returns a HTTPError with code, msg is the status text of code
+// if not given.
+func NewHTTPError(code int, msg ...string) *HTTPError {
+	he := &HTTPError{Code: code, Msg: http.StatusText(code)}
+	if len(msg) != 0 {
+		he.Msg = msg[0]
+	}
+	return he
+}
+
+// Error implements the error interface.
+func (he *HTTPError) Error() string {
+	return he.Msg
+}
+
 // SuccessCB is the c.Success() callback.
 // NOTE: DO NOT USE DEFAULT, MAKE IT YOURS.
 var SuccessCB = func(*Context, interface{}) error { return nil }
@@ -43,7 +64,11 @@ var ErrorCB = func(c *Context, code int, msg interface{}) error {
 // ErrorHandler is the centralized error handler.
 // NOTE: DO NOT USE DEFAULT, MAKE IT YOURS.
 var ErrorHandler = func(c *Context, err error) {
-	if err == ErrNotFound {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		c.SetStatusCode(he.Code)
+		c.Error(he.Code, he)
+	} else if err == ErrNotFound {
 		c.SetStatusCode(http.StatusNotFound)
 		c.Error(http.StatusNotFound, err)
 	} else if err == ErrMethodNotAllow {
@@ -0,0 +1,381 @@
+// Package ctxtest provides the utilities for testing ctx apps and handlers.
+//
+// Test an app through its router:
+//
+//	ctxtest.New(ctx.App()).POST("/users").WithJSON(user).
+//		Expect(t).Status(201).JSON(`{"id":1}`)
+//
+// Test a single Handler with preset params and store values:
+//
+//	ctxtest.NewRequest("GET", "/users/1").WithParam("id", "1").
+//		WithValue("user", u).Handle(t, getUser).Status(200)
+package ctxtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/BouncyElf/ctx"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Client sends the requests to an http.Handler without the network.
+type Client struct {
+	h http.Handler
+	// Header is the default headers of the requests.
+	Header http.Header
+}
+
+// New returns a Client of h, e.g. ctxtest.New(ctx.App()).
+func New(h http.Handler) *Client {
+	return &Client{h: h, Header: make(http.Header)}
+}
+
+// Request returns a Request with method and target, the path with the
+// optional query.
+func (cl *Client) Request(method, target string) *Request {
+	r := NewRequest(method, target)
+	r.client = cl
+	for k, v := range cl.Header {
+		r.header[k] = append([]string(nil), v...)
+	}
+	return r
+}
+
+// GET returns a GET Request of target.
+func (cl *Client) GET(target string) *Request {
+	return cl.Request(http.MethodGet, target)
+}
+
+// POST returns a POST Request of target.
+func (cl *Client) POST(target string) *Request {
+	return cl.Request(http.MethodPost, target)
+}
+
+// PUT returns a PUT Request of target.
+func (cl *Client) PUT(target string) *Request {
+	return cl.Request(http.MethodPut, target)
+}
+
+// PATCH returns a PATCH Request of target.
+func (cl *Client) PATCH(target string) *Request {
+	return cl.Request(http.MethodPatch, target)
+}
+
+// DELETE returns a DELETE Request of target.
+func (cl *Client) DELETE(target string) *Request {
+	return cl.Request(http.MethodDelete, target)
+}
+
+// HEAD returns a HEAD Request of target.
+func (cl *Client) HEAD(target string) *Request {
+	return cl.Request(http.MethodHead, target)
+}
+
+// OPTIONS returns a OPTIONS Request of target.
+func (cl *Client) OPTIONS(target string) *Request {
+	return cl.Request(http.MethodOptions, target)
+}
+
+// Request is the builder of a test request.
+type Request struct {
+	client  *Client
+	method  string
+	target  string
+	header  http.Header
+	query   url.Values
+	cookies []*http.Cookie
+	body    []byte
+	params  httprouter.Params
+	values  ctx.Map
+	err     error
+}
+
+// NewRequest returns a Request without a Client, for Handle and Context.
+func NewRequest(method, target string) *Request {
+	return &Request{
+		method: method,
+		target: target,
+		header: make(http.Header),
+		query:  make(url.Values),
+		values: make(ctx.Map),
+	}
+}
+
+// WithHeader sets the header k to v.
+func (r *Request) WithHeader(k, v string) *Request {
+	r.header.Set(k, v)
+	return r
+}
+
+// WithQuery adds v to the query parameter k.
+func (r *Request) WithQuery(k, v string) *Request {
+	r.query.Add(k, v)
+	return r
+}
+
+// WithCookie adds a cookie.
+func (r *Request) WithCookie(name, value string) *Request {
+	r.cookies = append(r.cookies, &http.Cookie{Name: name, Value: value})
+	return r
+}
+
+// WithBody sets the body with the Content-Type contentType.
+func (r *Request) WithBody(contentType string, body []byte) *Request {
+	r.header.Set("Content-Type", contentType)
+	r.body = body
+	return r
+}
+
+// WithJSON sets the body to v in JSON.
+func (r *Request) WithJSON(v interface{}) *Request {
+	b, err := json.Marshal(v)
+	if err != nil {
+		r.err = err
+	}
+	return r.WithBody("application/json", b)
+}
+
+// WithForm sets the body to the url encoded form.
+func (r *Request) WithForm(form url.Values) *Request {
+	return r.WithBody("application/x-www-form-urlencoded", []byte(form.Encode()))
+}
+
+// WithParam sets the router param k to v, for Handle and Context.
+func (r *Request) WithParam(k, v string) *Request {
+	r.params = append(r.params, httprouter.Param{Key: k, Value: v})
+	return r
+}
+
+// WithValue sets the store value k to v, see ctx.Context.Set. It's for
+// Handle and Context.
+func (r *Request) WithValue(k string, v interface{}) *Request {
+	r.values[k] = v
+	return r
+}
+
+// HTTPRequest returns the built http.Request.
+func (r *Request) HTTPRequest() *http.Request {
+	target := r.target
+	if len(r.query) != 0 {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + r.query.Encode()
+	}
+	req := httptest.NewRequest(r.method, target, bytes.NewReader(r.body))
+	req.Header = r.header.Clone()
+	for _, cookie := range r.cookies {
+		req.AddCookie(cookie)
+	}
+	if len(r.params) != 0 {
+		req = req.WithContext(context.WithValue(req.Context(),
+			httprouter.ParamsKey, r.params))
+	}
+	return req
+}
+
+// Expect sends the request by the Client and returns the response for
+// assertions.
+func (r *Request) Expect(t testing.TB) *Response {
+	t.Helper()
+	if r.client == nil {
+		t.Fatalf("ctxtest: %s %s has no client", r.method, r.target)
+	}
+	if r.err != nil {
+		t.Fatalf("ctxtest: build request error: %v", r.err)
+	}
+	rec := httptest.NewRecorder()
+	r.client.h.ServeHTTP(rec, r.HTTPRequest())
+	return &Response{t: t, Recorder: rec}
+}
+
+// Context returns a ctx.Context of the request with the params and the store
+// values, and the recorder of its response.
+func (r *Request) Context() (*ctx.Context, *httptest.ResponseRecorder) {
+	rec := httptest.NewRecorder()
+	c := ctx.NewRequestContext(rec, r.HTTPRequest())
+	for k, v := range r.values {
+		c.Set(k, v)
+	}
+	return c, rec
+}
+
+// Handle runs h with the Context of the request and returns the response
+// for assertions. The error of h is passed to ctx.ErrorHandler and kept in
+// Response.Err.
+func (r *Request) Handle(t testing.TB, h ctx.Handler) *Response {
+	t.Helper()
+	if r.err != nil {
+		t.Fatalf("ctxtest: build request error: %v", r.err)
+	}
+	c, rec := r.Context()
+	res := &Response{t: t, Recorder: rec}
+	ctx.Handler(func(c *ctx.Context) error {
+		res.Err = h(c)
+		return res.Err
+	}).Serve(c)
+	return res
+}
+
+// Response is the recorded response with the assertions. The assertions
+// report the failures with t.Errorf and return the Response for chaining.
+type Response struct {
+	t        testing.TB
+	Recorder *httptest.ResponseRecorder
+	// Err is the error returned by the Handler of Request.Handle.
+	Err error
+}
+
+// Status asserts the status code.
+func (res *Response) Status(code int) *Response {
+	res.t.Helper()
+	if res.Recorder.Code != code {
+		res.t.Errorf("ctxtest: status is %d, expected %d, body: %s",
+			res.Recorder.Code, code, res.Recorder.Body)
+	}
+	return res
+}
+
+// Header asserts the response header k is v.
+func (res *Response) Header(k, v string) *Response {
+	res.t.Helper()
+	if got := res.Recorder.Header().Get(k); got != v {
+		res.t.Errorf("ctxtest: header %s is %q, expected %q", k, got, v)
+	}
+	return res
+}
+
+// HeaderContains asserts the response header k contains sub.
+func (res *Response) HeaderContains(k, sub string) *Response {
+	res.t.Helper()
+	if got := res.Recorder.Header().Get(k); !strings.Contains(got, sub) {
+		res.t.Errorf("ctxtest: header %s is %q, expected to contain %q", k, got, sub)
+	}
+	return res
+}
+
+// Cookie asserts the response sets the cookie name to value.
+func (res *Response) Cookie(name, value string) *Response {
+	res.t.Helper()
+	cookie := res.cookie(name)
+	if cookie == nil {
+		res.t.Errorf("ctxtest: cookie %s is not set", name)
+	} else if cookie.Value != value {
+		res.t.Errorf("ctxtest: cookie %s is %q, expected %q", name, cookie.Value, value)
+	}
+	return res
+}
+
+// HasCookie asserts the response sets the cookie name and returns it, nil if
+// not set.
+func (res *Response) HasCookie(name string) *http.Cookie {
+	res.t.Helper()
+	cookie := res.cookie(name)
+	if cookie == nil {
+		res.t.Errorf("ctxtest: cookie %s is not set", name)
+	}
+	return cookie
+}
+
+// NoCookie asserts the response doesn't set the cookie name.
+func (res *Response) NoCookie(name string) *Response {
+	res.t.Helper()
+	if res.cookie(name) != nil {
+		res.t.Errorf("ctxtest: cookie %s is set", name)
+	}
+	return res
+}
+
+// cookie returns the last cookie named name in the response.
+func (res *Response) cookie(name string) *http.Cookie {
+	var found *http.Cookie
+	for _, cookie := range res.Recorder.Result().Cookies() {
+		if cookie.Name == name {
+			found = cookie
+		}
+	}
+	return found
+}
+
+// Body asserts the body is s.
+func (res *Response) Body(s string) *Response {
+	res.t.Helper()
+	if got := res.Recorder.Body.String(); got != s {
+		res.t.Errorf("ctxtest: body is %q, expected %q", got, s)
+	}
+	return res
+}
+
+// BodyContains asserts the body contains sub.
+func (res *Response) BodyContains(sub string) *Response {
+	res.t.Helper()
+	if got := res.Recorder.Body.String(); !strings.Contains(got, sub) {
+		res.t.Errorf("ctxtest: body is %q, expected to contain %q", got, sub)
+	}
+	return res
+}
+
+// JSON asserts the body is the JSON equal to v. v is the raw JSON if it's a
+// string or a []byte, otherwise it's marshaled.
+func (res *Response) JSON(v interface{}) *Response {
+	res.t.Helper()
+	var expected []byte
+	switch v := v.(type) {
+	case string:
+		expected = []byte(v)
+	case []byte:
+		expected = v
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			res.t.Errorf("ctxtest: marshal expected json error: %v", err)
+			return res
+		}
+		expected = b
+	}
+	var want, got interface{}
+	if err := json.Unmarshal(expected, &want); err != nil {
+		res.t.Errorf("ctxtest: invalid expected json: %v", err)
+		return res
+	}
+	if err := json.Unmarshal(res.Recorder.Body.Bytes(), &got); err != nil {
+		res.t.Errorf("ctxtest: invalid json body %q: %v", res.Recorder.Body, err)
+		return res
+	}
+	if !reflect.DeepEqual(want, got) {
+		res.t.Errorf("ctxtest: json body is %s, expected %s", res.Recorder.Body, expected)
+	}
+	return res
+}
+
+// DecodeJSON decodes the JSON body into v.
+func (res *Response) DecodeJSON(v interface{}) *Response {
+	res.t.Helper()
+	if err := json.Unmarshal(res.Recorder.Body.Bytes(), v); err != nil {
+		res.t.Errorf("ctxtest: decode json body %q error: %v", res.Recorder.Body, err)
+	}
+	return res
+}
+
+// NoError asserts the Handler of Request.Handle returns nil.
+func (res *Response) NoError() *Response {
+	res.t.Helper()
+	if res.Err != nil {
+		res.t.Errorf("ctxtest: handler error: %v", res.Err)
+	}
+	return res
+}
+
+// BodyBytes returns the body.
+func (res *Response) BodyBytes() []byte {
+	return res.Recorder.Body.Bytes()
+}
@@ -0,0 +1,87 @@
+package ctxtest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/BouncyElf/ctx"
+)
+
+func init() {
+	ctx.POST("/users/:id", func(c *ctx.Context) error {
+		var body ctx.Map
+		if err := json.NewDecoder(c.ReqBody()).Decode(&body); err != nil {
+			return err
+		}
+		c.SetCookie(&http.Cookie{Name: "seen", Value: c.Params("id")})
+		c.ResHeader().Set("X-Token", c.ReqHeader().Get("X-Token"))
+		return c.Json(ctx.Map{
+			"id":    c.Params("id"),
+			"name":  body["name"],
+			"q":     c.Query("q"),
+			"token": c.ReqHeader().Get("X-Token"),
+		})
+	})
+	ctx.POST("/form", func(c *ctx.Context) error {
+		cookie, _ := c.Cookie("session")
+		return c.String(c.Req.PostFormValue("a") + cookie.Value)
+	})
+}
+
+func TestClient(t *testing.T) {
+	client := New(ctx.App())
+	client.Header.Set("X-Token", "t")
+	client.POST("/users/1").WithQuery("q", "v").WithJSON(ctx.Map{"name": "n"}).
+		Expect(t).
+		Status(200).
+		Header("X-Token", "t").
+		HeaderContains("Content-Type", "json").
+		Cookie("seen", "1").
+		NoCookie("other").
+		JSON(`{"id":"1","name":"n","q":"v","token":"t"}`).
+		JSON(map[string]string{"id": "1", "name": "n", "q": "v", "token": "t"})
+
+	client.POST("/form").WithForm(url.Values{"a": {"b"}}).WithCookie("session", "s").
+		Expect(t).Status(200).Body("bs").BodyContains("b")
+
+	client.GET("/none").Expect(t).Status(404)
+}
+
+func TestHandle(t *testing.T) {
+	var got struct {
+		ID   string `json:"id"`
+		User string `json:"user"`
+	}
+	NewRequest(http.MethodGet, "/users/1").
+		WithParam("id", "1").
+		WithValue("user", "alice").
+		Handle(t, func(c *ctx.Context) error {
+			return c.Json(ctx.Map{"id": c.Params("id"), "user": c.MustGet("user")})
+		}).
+		NoError().
+		Status(200).
+		DecodeJSON(&got)
+	if got.ID != "1" || got.User != "alice" {
+		t.Errorf("got %+v", got)
+	}
+
+	err := errors.New("failed")
+	res := NewRequest(http.MethodGet, "/").Handle(t, func(c *ctx.Context) error {
+		return err
+	}).Status(500)
+	if res.Err != err {
+		t.Errorf("got error %v", res.Err)
+	}
+
+	c, rec := NewRequest(http.MethodGet, "/").WithValue("k", "v").Context()
+	if v, _ := c.Get("k"); v != "v" {
+		t.Errorf("got value %v", v)
+	}
+	c.String("ok")
+	if rec.Body.String() != "ok" {
+		t.Errorf("got body %q", rec.Body)
+	}
+}
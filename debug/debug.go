@@ -0,0 +1,126 @@
+// Package debug provides the debug endpoints of ctx apps, the pprof profiles,
+// the expvar variables and the runtime stats. It's a separate package since
+// importing net/http/pprof and expvar registers their handlers on
+// http.DefaultServeMux, so the endpoints are only there if it's imported.
+package debug
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/BouncyElf/ctx"
+)
+
+// startTime is the time the process started, for the uptime.
+var startTime = time.Now()
+
+// Register registers the debug endpoints in g, they are protected by guards,
+// ctx.LocalOnly if no guard. e.g. debug.Register(ctx.Group("/debug"), auth)
+//
+//	/pprof/      the pprof index and profiles, for go tool pprof
+//	/vars        the expvar variables
+//	/goroutines  the stack traces of all goroutines
+//	/stats       the runtime stats in JSON
+func Register(g *ctx.GroupRouter, guards ...ctx.Handler) []*ctx.Route {
+	if len(guards) == 0 {
+		guards = []ctx.Handler{ctx.LocalOnly}
+	}
+	return []*ctx.Route{
+		g.GET("/pprof/*name", pprofHandler, guards...).Hidden(),
+		g.POST("/pprof/*name", pprofHandler, guards...).Hidden(),
+		g.GET("/vars", httpHandler(expvar.Handler()), guards...).Hidden(),
+		g.GET("/goroutines", goroutinesHandler, guards...).Hidden(),
+		g.GET("/stats", statsHandler, guards...).Hidden(),
+	}
+}
+
+// httpHandler converts h into a ctx.Handler.
+func httpHandler(h http.Handler) ctx.Handler {
+	return func(c *ctx.Context) error {
+		h.ServeHTTP(c.Res, c.Req)
+		return nil
+	}
+}
+
+// pprofHandler serves the pprof index and profiles under any prefix, while
+// pprof.Index only serves the named profiles under /debug/pprof/.
+func pprofHandler(c *ctx.Context) error {
+	name := strings.TrimPrefix(c.Params("name"), "/")
+	switch name {
+	case "":
+		if c.Method() != "GET" {
+			return ctx.ErrMethodNotAllow
+		}
+		pprof.Index(c.Res, c.Req)
+	case "cmdline":
+		pprof.Cmdline(c.Res, c.Req)
+	case "profile":
+		pprof.Profile(c.Res, c.Req)
+	case "symbol":
+		pprof.Symbol(c.Res, c.Req)
+	case "trace":
+		pprof.Trace(c.Res, c.Req)
+	default:
+		if c.Method() != "GET" {
+			return ctx.ErrMethodNotAllow
+		}
+		pprof.Handler(name).ServeHTTP(c.Res, c.Req)
+	}
+	return nil
+}
+
+// goroutinesHandler responses the stack traces of all goroutines.
+func goroutinesHandler(c *ctx.Context) error {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	return c.Write(buf)
+}
+
+// statsHandler responses the runtime stats.
+func statsHandler(c *ctx.Context) error {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return c.Json(ctx.Map{
+		"version":    runtime.Version(),
+		"goos":       runtime.GOOS,
+		"goarch":     runtime.GOARCH,
+		"cpus":       runtime.NumCPU(),
+		"gomaxprocs": runtime.GOMAXPROCS(0),
+		"goroutines": runtime.NumGoroutine(),
+		"cgo_calls":  runtime.NumCgoCall(),
+		"uptime":     time.Since(startTime).String(),
+		"memory": ctx.Map{
+			"alloc":         m.Alloc,
+			"total_alloc":   m.TotalAlloc,
+			"sys":           m.Sys,
+			"heap_alloc":    m.HeapAlloc,
+			"heap_sys":      m.HeapSys,
+			"heap_idle":     m.HeapIdle,
+			"heap_inuse":    m.HeapInuse,
+			"heap_released": m.HeapReleased,
+			"heap_objects":  m.HeapObjects,
+			"stack_inuse":   m.StackInuse,
+			"mallocs":       m.Mallocs,
+			"frees":         m.Frees,
+		},
+		"gc": ctx.Map{
+			"num_gc":         m.NumGC,
+			"num_forced_gc":  m.NumForcedGC,
+			"pause_total_ns": m.PauseTotalNs,
+			"last_gc":        time.Unix(0, int64(m.LastGC)),
+			"next_gc":        m.NextGC,
+			"cpu_fraction":   m.GCCPUFraction,
+		},
+	})
+}
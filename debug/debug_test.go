@@ -0,0 +1,57 @@
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/BouncyElf/ctx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister(t *testing.T) {
+	Register(ctx.Group("/debug"))
+	Register(ctx.Group("/admin/debug"), ctx.APIKey(&ctx.APIKeyConfig{
+		Keys: map[string]*ctx.Principal{"key": {ID: "admin"}},
+	}))
+
+	request := func(path, remote string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remote
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		res := httptest.NewRecorder()
+		ctx.App().ServeHTTP(res, req)
+		return res
+	}
+	local := "127.0.0.1:1234"
+
+	res := request("/debug/pprof/", local, nil)
+	assert.Equal(t, 200, res.Code)
+	assert.Contains(t, res.Body.String(), "goroutine?debug=1")
+	res = request("/debug/pprof/goroutine?debug=1", local, nil)
+	assert.Equal(t, 200, res.Code)
+	assert.Contains(t, res.Body.String(), "goroutine profile")
+	res = request("/debug/pprof/cmdline", local, nil)
+	assert.Equal(t, 200, res.Code)
+	res = request("/debug/vars", local, nil)
+	assert.Equal(t, 200, res.Code)
+	assert.Contains(t, res.Body.String(), "memstats")
+	res = request("/debug/goroutines", local, nil)
+	assert.Equal(t, 200, res.Code)
+	assert.Contains(t, res.Body.String(), "TestRegister")
+	res = request("/debug/stats", "[::1]:1234", nil)
+	assert.Equal(t, 200, res.Code)
+	stats := ctx.Map{}
+	assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &stats))
+	assert.NotZero(t, stats["goroutines"])
+
+	res = request("/debug/stats", "192.0.2.1:1234", nil)
+	assert.Equal(t, http.StatusForbidden, res.Code)
+	res = request("/admin/debug/stats", "192.0.2.1:1234", nil)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	res = request("/admin/debug/stats", "192.0.2.1:1234", map[string]string{"X-API-Key": "key"})
+	assert.Equal(t, 200, res.Code)
+}
@@ -0,0 +1,127 @@
+package ctx
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// ETagConfig is the config of ETag.
+type ETagConfig struct {
+	// Weak makes the weak ETags, e.g. W/"xyz", for the responses which are
+	// equivalent but not byte identical, e.g. they may be compressed later.
+	Weak bool
+}
+
+// ETag returns the middleware which buffers the 200 responses of GET and
+// HEAD requests and sets the ETag header to the hash of the body, unless the
+// handler sets it. Then it responses 304 if If-None-Match matches, and 412
+// if If-Match doesn't match. The flushed responses are not tagged, e.g. the
+// server-sent events, neither are the HEAD responses without the ETag set by
+// the handler, their body is empty. The handlers changing the resources check
+// the conditional headers by c.CheckPreconditions.
+// NOTE: the whole body is kept in memory, don't use it for large downloads.
+func ETag(cfg *ETagConfig) Handler {
+	conf := new(ETagConfig)
+	if cfg != nil {
+		*conf = *cfg
+	}
+	return func(c *Context) error {
+		if c.Req.Method != http.MethodGet && c.Req.Method != http.MethodHead {
+			return nil
+		}
+		c.bufferResponse(func() {
+			c.checkETag(conf)
+		})
+		return nil
+	}
+}
+
+// checkETag sets the ETag of the buffered response and checks the
+// conditional headers of the request.
+func (c *Context) checkETag(conf *ETagConfig) {
+	if c.res.status != http.StatusOK {
+		return
+	}
+	h := c.ResHeader()
+	etag := h.Get("ETag")
+	if etag == "" {
+		if c.Req.Method == http.MethodHead {
+			return
+		}
+		etag = newETag(c.res.buf.Bytes(), conf.Weak)
+		h.Set("ETag", etag)
+	}
+	if im := c.Req.Header.Get("If-Match"); im != "" && !etagMatch(im, etag, true) {
+		c.res.status = http.StatusPreconditionFailed
+		c.res.buf.Reset()
+		c.res.buf.WriteString(http.StatusText(http.StatusPreconditionFailed))
+		h.Set("Content-Type", "text/plain; charset=utf-8")
+		h.Del("Content-Length")
+		return
+	}
+	if inm := c.Req.Header.Get("If-None-Match"); inm != "" && etagMatch(inm, etag, false) {
+		c.res.status = http.StatusNotModified
+		c.res.buf.Reset()
+		h.Del("Content-Type")
+		h.Del("Content-Length")
+	}
+}
+
+// CheckPreconditions sets the ETag header to etag, the current ETag of the
+// resource, and checks the conditional headers of the request. It returns a
+// 412 HTTPError if If-Match doesn't match, or If-None-Match matches for the
+// methods other than GET and HEAD, and a 304 HTTPError if If-None-Match
+// matches for GET and HEAD. Call it before changing the resource, e.g.
+//
+//	if err := c.CheckPreconditions(etag); err != nil {
+//		return err
+//	}
+func (c *Context) CheckPreconditions(etag string) error {
+	c.ResHeader().Set("ETag", etag)
+	if im := c.Req.Header.Get("If-Match"); im != "" && !etagMatch(im, etag, true) {
+		return NewHTTPError(http.StatusPreconditionFailed)
+	}
+	if inm := c.Req.Header.Get("If-None-Match"); inm != "" && etagMatch(inm, etag, false) {
+		if c.Req.Method == http.MethodGet || c.Req.Method == http.MethodHead {
+			return NewHTTPError(http.StatusNotModified)
+		}
+		return NewHTTPError(http.StatusPreconditionFailed)
+	}
+	return nil
+}
+
+// newETag returns the ETag of body.
+func newETag(body []byte, weak bool) string {
+	sum := sha256.Sum256(body)
+	etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+	if weak {
+		return "W/" + etag
+	}
+	return etag
+}
+
+// etagMatch returns if the ETag list of the header matches etag. The weak
+// ETags never match in the strong comparison, see RFC 9110 section 8.8.3.2.
+func etagMatch(header, etag string, strong bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if strong && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strong {
+			if tag == etag {
+				return true
+			}
+			continue
+		}
+		if strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
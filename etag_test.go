@@ -0,0 +1,170 @@
+package ctx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETag(t *testing.T) {
+	etagHandler := func(cfg *ETagConfig, h Handler) http.Handler {
+		return Handler(func(c *Context) error {
+			if err := ETag(cfg)(c); err != nil {
+				return err
+			}
+			return h(c)
+		})
+	}
+	do := func(h http.Handler, method string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		return res
+	}
+	h := etagHandler(nil, func(c *Context) error {
+		return c.String("hello")
+	})
+
+	res := do(h, http.MethodGet)
+	etag := res.Header().Get("ETag")
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "hello", res.Body.String())
+	assert.Equal(t, "5", res.Header().Get("Content-Length"))
+	assert.True(t, strings.HasPrefix(etag, `"`))
+	// the body of HEAD is empty
+	assert.Empty(t, do(h, http.MethodHead).Header().Get("ETag"))
+
+	res = do(h, http.MethodGet, "If-None-Match", `"x", `+etag)
+	assert.Equal(t, http.StatusNotModified, res.Code)
+	assert.Empty(t, res.Body.String())
+	assert.Equal(t, etag, res.Header().Get("ETag"))
+	assert.Empty(t, res.Header().Get("Content-Type"))
+	assert.Equal(t, http.StatusNotModified, do(h, http.MethodGet, "If-None-Match", "*").Code)
+	assert.Equal(t, http.StatusNotModified, do(h, http.MethodGet, "If-None-Match", "W/"+etag).Code)
+	assert.Equal(t, 200, do(h, http.MethodGet, "If-None-Match", `"x"`).Code)
+
+	assert.Equal(t, 200, do(h, http.MethodGet, "If-Match", etag).Code)
+	assert.Equal(t, http.StatusPreconditionFailed, do(h, http.MethodGet, "If-Match", `"x"`).Code)
+	assert.Equal(t, http.StatusPreconditionFailed, do(h, http.MethodGet, "If-Match", "W/"+etag).Code)
+
+	// weak
+	weak := etagHandler(&ETagConfig{Weak: true}, func(c *Context) error {
+		return c.String("hello")
+	})
+	res = do(weak, http.MethodGet)
+	assert.Equal(t, "W/"+etag, res.Header().Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, do(weak, http.MethodGet, "If-None-Match", etag).Code)
+	assert.Equal(t, http.StatusPreconditionFailed, do(weak, http.MethodGet, "If-Match", "W/"+etag).Code)
+
+	// the handler's ETag, the other statuses and methods
+	custom := etagHandler(nil, func(c *Context) error {
+		c.ResHeader().Set("ETag", `"v1"`)
+		return c.String("hello")
+	})
+	assert.Equal(t, `"v1"`, do(custom, http.MethodGet).Header().Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, do(custom, http.MethodGet, "If-None-Match", `"v1"`).Code)
+	assert.Equal(t, `"v1"`, do(custom, http.MethodHead).Header().Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, do(custom, http.MethodHead, "If-None-Match", `"v1"`).Code)
+	created := etagHandler(nil, func(c *Context) error {
+		c.SetStatusCode(http.StatusCreated)
+		return c.String("created")
+	})
+	res = do(created, http.MethodGet, "If-None-Match", "*")
+	assert.Equal(t, http.StatusCreated, res.Code)
+	assert.Empty(t, res.Header().Get("ETag"))
+	res = do(h, http.MethodPost, "If-None-Match", "*")
+	assert.Equal(t, 200, res.Code)
+	assert.Empty(t, res.Header().Get("ETag"))
+	notFound := etagHandler(nil, func(c *Context) error {
+		return NewHTTPError(http.StatusNotFound)
+	})
+	res = do(notFound, http.MethodGet)
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	assert.Empty(t, res.Header().Get("ETag"))
+
+	// flushed responses are streaming
+	stream := etagHandler(nil, func(c *Context) error {
+		c.Res.Write([]byte("a"))
+		if err := c.Flush(); err != nil {
+			return err
+		}
+		_, err := c.Res.Write([]byte("b"))
+		return err
+	})
+	res = do(stream, http.MethodGet)
+	assert.True(t, res.Flushed)
+	assert.Equal(t, "ab", res.Body.String())
+	assert.Empty(t, res.Header().Get("ETag"))
+}
+
+func TestCheckPreconditions(t *testing.T) {
+	updated := 0
+	h := Handler(func(c *Context) error {
+		if err := c.CheckPreconditions(`"v1"`); err != nil {
+			return err
+		}
+		updated++
+		return c.String("ok")
+	})
+	do := func(method string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		return res
+	}
+
+	res := do(http.MethodPut, "If-Match", `"v1"`)
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, `"v1"`, res.Header().Get("ETag"))
+	assert.Equal(t, 200, do(http.MethodDelete).Code)
+	assert.Equal(t, 2, updated)
+
+	assert.Equal(t, http.StatusPreconditionFailed, do(http.MethodPut, "If-Match", `"v0"`).Code)
+	assert.Equal(t, http.StatusPreconditionFailed, do(http.MethodPatch, "If-Match", `W/"v1"`).Code)
+	assert.Equal(t, http.StatusPreconditionFailed, do(http.MethodPut, "If-None-Match", "*").Code)
+	assert.Equal(t, http.StatusNotModified, do(http.MethodGet, "If-None-Match", `"v1"`).Code)
+	assert.Equal(t, http.StatusNotModified, do(http.MethodHead, "If-None-Match", `W/"v1"`).Code)
+	assert.Equal(t, 2, updated)
+	assert.Equal(t, 200, do(http.MethodPost, "If-None-Match", `"v0"`).Code)
+	assert.Equal(t, 3, updated)
+}
+
+func TestServeContent(t *testing.T) {
+	modtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	h := Handler(func(c *Context) error {
+		return c.ServeContent("report.csv", modtime, strings.NewReader("a,b\n1,2\n"))
+	})
+	do := func(header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		return res
+	}
+
+	res := do()
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "a,b\n1,2\n", res.Body.String())
+	assert.Contains(t, res.Header().Get("Content-Type"), "text/csv")
+	assert.Equal(t, modtime.Format(http.TimeFormat), res.Header().Get("Last-Modified"))
+
+	res = do("Range", "bytes=4-6")
+	assert.Equal(t, http.StatusPartialContent, res.Code)
+	assert.Equal(t, "1,2", res.Body.String())
+	assert.Equal(t, "bytes 4-6/8", res.Header().Get("Content-Range"))
+
+	res = do("If-Modified-Since", modtime.Format(http.TimeFormat))
+	assert.Equal(t, http.StatusNotModified, res.Code)
+}
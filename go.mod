@@ -3,6 +3,13 @@ module github.com/BouncyElf/ctx
 require (
 	github.com/julienschmidt/httprouter v1.2.0
 	github.com/stretchr/testify v1.3.0
+	golang.org/x/net v0.50.0
 )
 
-go 1.13
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/text v0.34.0 // indirect
+)
+
+go 1.24.0
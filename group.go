@@ -9,10 +9,12 @@ type GroupRouter struct {
 // Group returns a new GroupRouter with prefix and optinal middleware.
 func Group(prefix string, hs ...Handler) *GroupRouter {
 	r := &router{
-		r:    routerIns.r,
-		prev: routerIns.prev,
-		next: routerIns.next,
-		s:    nil,
+		r:        routerIns.r,
+		prev:     routerIns.prev,
+		next:     routerIns.next,
+		s:        nil,
+		group:    prefix,
+		requires: append([]Policy(nil), routerIns.requires...),
 	}
 	return &GroupRouter{
 		prefix: prefix,
@@ -24,10 +26,12 @@ func Group(prefix string, hs ...Handler) *GroupRouter {
 // optinal middleware based on g.
 func (g *GroupRouter) Group(prefix string, hs ...Handler) *GroupRouter {
 	r := &router{
-		r:    routerIns.r,
-		prev: g.r.prev,
-		next: g.r.next,
-		s:    nil,
+		r:        routerIns.r,
+		prev:     g.r.prev,
+		next:     g.r.next,
+		s:        nil,
+		group:    g.prefix + prefix,
+		requires: append([]Policy(nil), g.r.requires...),
 	}
 	return &GroupRouter{
 		prefix: g.prefix + prefix,
@@ -65,36 +69,36 @@ func (g *GroupRouter) Next(hs ...Handler) {
 }
 
 // g.GET is same as GET, it register a GET route with g.prefix+path.
-func (g *GroupRouter) GET(path string, h Handler, mhs ...Handler) {
-	g.r.push("GET", g.prefix+path, h, mhs...)
+func (g *GroupRouter) GET(path string, h Handler, mhs ...Handler) *Route {
+	return g.r.push("GET", g.prefix+path, h, mhs...)
 }
 
 // g.POST is same as POST, it register a POST route with g.prefix+path.
-func (g *GroupRouter) POST(path string, h Handler, mhs ...Handler) {
-	g.r.push("POST", g.prefix+path, h, mhs...)
+func (g *GroupRouter) POST(path string, h Handler, mhs ...Handler) *Route {
+	return g.r.push("POST", g.prefix+path, h, mhs...)
 }
 
 // g.HEAD is same as HEAD, it register a HEAD route with g.prefix+path.
-func (g *GroupRouter) HEAD(path string, h Handler, mhs ...Handler) {
-	g.r.push("HEAD", g.prefix+path, h, mhs...)
+func (g *GroupRouter) HEAD(path string, h Handler, mhs ...Handler) *Route {
+	return g.r.push("HEAD", g.prefix+path, h, mhs...)
 }
 
 // g.OPTIONS is same as OPTIONS, it register a OPTIONS route with g.prefix+path.
-func (g *GroupRouter) OPTIONS(path string, h Handler, mhs ...Handler) {
-	g.r.push("OPTIONS", g.prefix+path, h, mhs...)
+func (g *GroupRouter) OPTIONS(path string, h Handler, mhs ...Handler) *Route {
+	return g.r.push("OPTIONS", g.prefix+path, h, mhs...)
 }
 
 // g.PUT is same as PUT, it register a PUT route with g.prefix+path.
-func (g *GroupRouter) PUT(path string, h Handler, mhs ...Handler) {
-	g.r.push("PUT", g.prefix+path, h, mhs...)
+func (g *GroupRouter) PUT(path string, h Handler, mhs ...Handler) *Route {
+	return g.r.push("PUT", g.prefix+path, h, mhs...)
 }
 
 // g.PATCH is same as PATCH, it register a PATCH route with g.prefix+path.
-func (g *GroupRouter) PATCH(path string, h Handler, mhs ...Handler) {
-	g.r.push("PATCH", g.prefix+path, h, mhs...)
+func (g *GroupRouter) PATCH(path string, h Handler, mhs ...Handler) *Route {
+	return g.r.push("PATCH", g.prefix+path, h, mhs...)
 }
 
 // g.DELETE is same as DELETE, it register a DELETE route with g.prefix+path.
-func (g *GroupRouter) DELETE(path string, h Handler, mhs ...Handler) {
-	g.r.push("DELETE", g.prefix+path, h, mhs...)
+func (g *GroupRouter) DELETE(path string, h Handler, mhs ...Handler) *Route {
+	return g.r.push("DELETE", g.prefix+path, h, mhs...)
 }
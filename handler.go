@@ -15,16 +15,33 @@ type Handlers []Handler
 func (h Handler) NewHttpHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		c := getContext(w, r)
-		defer func() {
-			if msg := recover(); msg != nil {
-				PanicHandler(c, msg)
-			}
-			contextPool.Put(c)
-		}()
-		err := h(c)
-		if err != nil {
-			ErrorHandler(c, err)
+		defer contextPool.Put(c)
+		h.Serve(c)
+	}
+}
+
+// Serve runs h with c as a whole request, the error and the panic are passed
+// to ErrorHandler and PanicHandler. It's for the Context made by
+// NewRequestContext.
+func (h Handler) Serve(c *Context) {
+	defer func() {
+		if msg := recover(); msg != nil {
+			c.recovered = msg
+			PanicHandler(c, msg)
+		}
+		c.res.runBefore()
+		c.res.runSend()
+		c.res.runAfter()
+		// c.Req may be a copy the server doesn't know, so remove the temp
+		// files of the multipart form here
+		if c.Req != nil && c.Req.MultipartForm != nil {
+			c.Req.MultipartForm.RemoveAll()
 		}
+	}()
+	err := h(c)
+	if err != nil {
+		c.err = err
+		ErrorHandler(c, err)
 	}
 }
 
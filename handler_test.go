@@ -30,3 +30,13 @@ func TestHandlerChain(t *testing.T) {
 func TestErrorPanicHandler(t *testing.T) {
 	// TODO:
 }
+
+func TestHTTPError(t *testing.T) {
+	resetRouter()
+	GET("/e", func(c *Context) error {
+		return NewHTTPError(http.StatusTeapot)
+	})
+	res := serve(http.MethodGet, "/e")
+	assert.Equal(t, http.StatusTeapot, res.Code)
+	assert.Equal(t, "I'm a teapot\n", res.Body.String())
+}
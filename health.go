@@ -0,0 +1,199 @@
+package ctx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HealthCheck is a named check of a component, e.g. the database.
+type HealthCheck struct {
+	Name string
+	// Check returns nil if the component is healthy.
+	Check func(ctx context.Context) error
+	// Timeout is the timeout of Check, default is 5s.
+	Timeout time.Duration
+	// Critical makes the endpoints fail when the check fails, otherwise they
+	// only warn.
+	Critical bool
+	// Liveness adds the check to /livez. A liveness check should only fail if
+	// the process must be restarted, so the dependencies are not.
+	Liveness bool
+}
+
+var (
+	healthMu     sync.RWMutex
+	healthChecks []HealthCheck
+	// draining is set when Shutdown begins.
+	draining atomic.Bool
+)
+
+// AddHealthCheck registers check, it replaces the check with the same name.
+func AddHealthCheck(check HealthCheck) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	for i := range healthChecks {
+		if healthChecks[i].Name == check.Name {
+			healthChecks[i] = check
+			return
+		}
+	}
+	healthChecks = append(healthChecks, check)
+}
+
+// RemoveHealthCheck removes the check named name.
+func RemoveHealthCheck(name string) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	for i := range healthChecks {
+		if healthChecks[i].Name == name {
+			healthChecks = append(healthChecks[:i], healthChecks[i+1:]...)
+			return
+		}
+	}
+}
+
+// The status of the checks and the endpoints.
+const (
+	HealthPass = "pass"
+	HealthWarn = "warn"
+	HealthFail = "fail"
+)
+
+// HealthResult is the result of a check.
+type HealthResult struct {
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+// HealthReport is the response of the health endpoints.
+type HealthReport struct {
+	Status   string                   `json:"status"`
+	Draining bool                     `json:"draining,omitempty"`
+	Checks   map[string]*HealthResult `json:"checks"`
+}
+
+// runHealthChecks runs the checks concurrently, only the liveness checks if
+// liveness is true.
+func runHealthChecks(ctx context.Context, liveness bool) *HealthReport {
+	healthMu.RLock()
+	checks := make([]HealthCheck, 0, len(healthChecks))
+	for _, check := range healthChecks {
+		if !liveness || check.Liveness {
+			checks = append(checks, check)
+		}
+	}
+	healthMu.RUnlock()
+
+	report := &HealthReport{
+		Status: HealthPass,
+		Checks: make(map[string]*HealthResult, len(checks)),
+	}
+	results := make([]*HealthResult, len(checks))
+	wg := new(sync.WaitGroup)
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = checks[i].run(ctx)
+		}(i)
+	}
+	wg.Wait()
+	for i, r := range results {
+		report.Checks[checks[i].Name] = r
+		if r.Status == HealthPass {
+			continue
+		}
+		if r.Critical {
+			report.Status = HealthFail
+		} else if report.Status == HealthPass {
+			report.Status = HealthWarn
+		}
+	}
+	return report
+}
+
+// run runs the check with its timeout.
+func (check *HealthCheck) run(ctx context.Context) *HealthResult {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if msg := recover(); msg != nil {
+				done <- fmt.Errorf("panic: %v", msg)
+			}
+		}()
+		done <- check.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	r := &HealthResult{
+		Status:   HealthPass,
+		Critical: check.Critical,
+		Duration: time.Since(start).String(),
+	}
+	if err != nil {
+		r.Status = HealthFail
+		if !check.Critical {
+			r.Status = HealthWarn
+		}
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// writeHealth responses report, 503 if it fails.
+func writeHealth(c *Context, report *HealthReport) error {
+	code := http.StatusOK
+	if report.Status == HealthFail {
+		code = http.StatusServiceUnavailable
+	}
+	c.ResHeader().Set("Cache-Control", "no-store")
+	c.SetStatusCode(code)
+	return c.Json(report)
+}
+
+// HealthzHandler responses the report of all checks.
+func HealthzHandler(c *Context) error {
+	return writeHealth(c, runHealthChecks(c.Req.Context(), false))
+}
+
+// ReadyzHandler responses the report of all checks, it fails once Shutdown
+// begins draining.
+func ReadyzHandler(c *Context) error {
+	report := runHealthChecks(c.Req.Context(), false)
+	if draining.Load() {
+		report.Status = HealthFail
+		report.Draining = true
+	}
+	return writeHealth(c, report)
+}
+
+// LivezHandler responses the report of the liveness checks.
+func LivezHandler(c *Context) error {
+	return writeHealth(c, runHealthChecks(c.Req.Context(), true))
+}
+
+// ServeHealth registers the GET routes /healthz, /readyz and /livez.
+func ServeHealth() []*Route {
+	return []*Route{
+		GET("/healthz", HealthzHandler).Hidden(),
+		GET("/readyz", ReadyzHandler).Hidden(),
+		GET("/livez", LivezHandler).Hidden(),
+	}
+}
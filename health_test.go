@@ -0,0 +1,81 @@
+package ctx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func healthRequest(path string) (int, *HealthReport) {
+	res := httptest.NewRecorder()
+	routerIns.r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+	report := new(HealthReport)
+	json.Unmarshal(res.Body.Bytes(), report)
+	return res.Code, report
+}
+
+func TestHealth(t *testing.T) {
+	resetRouter()
+	draining.Store(false)
+	defer func() {
+		healthChecks = nil
+		draining.Store(false)
+	}()
+	ServeHealth()
+
+	code, report := healthRequest("/healthz")
+	assert.Equal(t, 200, code)
+	assert.Equal(t, HealthPass, report.Status)
+
+	var cacheErr error
+	AddHealthCheck(HealthCheck{Name: "process", Liveness: true, Critical: true,
+		Check: func(ctx context.Context) error { return nil }})
+	AddHealthCheck(HealthCheck{Name: "cache",
+		Check: func(ctx context.Context) error { return cacheErr }})
+	AddHealthCheck(HealthCheck{Name: "db", Critical: true, Timeout: 10 * time.Millisecond,
+		Check: func(ctx context.Context) error { return nil }})
+
+	code, report = healthRequest("/readyz")
+	assert.Equal(t, 200, code)
+	assert.Equal(t, HealthPass, report.Status)
+	assert.Len(t, report.Checks, 3)
+
+	cacheErr = errors.New("cache down")
+	code, report = healthRequest("/healthz")
+	assert.Equal(t, 200, code)
+	assert.Equal(t, HealthWarn, report.Status)
+	assert.Equal(t, "cache down", report.Checks["cache"].Error)
+
+	// the check ignoring ctx still times out
+	AddHealthCheck(HealthCheck{Name: "db", Critical: true, Timeout: 10 * time.Millisecond,
+		Check: func(ctx context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		}})
+	code, report = healthRequest("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, HealthFail, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["db"].Error)
+
+	code, report = healthRequest("/livez")
+	assert.Equal(t, 200, code)
+	assert.Len(t, report.Checks, 1)
+	assert.Equal(t, HealthPass, report.Checks["process"].Status)
+
+	RemoveHealthCheck("db")
+	RemoveHealthCheck("cache")
+	code, _ = healthRequest("/readyz")
+	assert.Equal(t, 200, code)
+	draining.Store(true)
+	code, report = healthRequest("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.True(t, report.Draining)
+	code, _ = healthRequest("/livez")
+	assert.Equal(t, 200, code)
+}
@@ -0,0 +1,79 @@
+package ctx
+
+import (
+	"net/http"
+	"net/netip"
+	"sync"
+)
+
+// IPFilter checks the client IP against the allow and deny lists of CIDRs or
+// IPs, both IPv4 and IPv6. The lists can be reloaded at runtime.
+type IPFilter struct {
+	mu    sync.RWMutex
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// NewIPFilter returns an IPFilter with the allow and deny lists. An IP is
+// allowed if it's not in deny, and it's in allow or allow is empty.
+func NewIPFilter(allow, deny []string) (*IPFilter, error) {
+	f := new(IPFilter)
+	if err := f.Reload(allow, deny); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reload replaces the allow and deny lists. The lists are unchanged if any
+// of them is invalid.
+func (f *IPFilter) Reload(allow, deny []string) error {
+	allowPrefixes, err := parsePrefixes(allow)
+	if err != nil {
+		return e("ip filter error", err)
+	}
+	denyPrefixes, err := parsePrefixes(deny)
+	if err != nil {
+		return e("ip filter error", err)
+	}
+	f.mu.Lock()
+	f.allow, f.deny = allowPrefixes, denyPrefixes
+	f.mu.Unlock()
+	return nil
+}
+
+// Allowed returns if ip is allowed. An invalid ip is only allowed if both
+// lists are empty.
+func (f *IPFilter) Allowed(ip string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return len(f.allow) == 0 && len(f.deny) == 0
+	}
+	if prefixesContain(f.deny, addr) {
+		return false
+	}
+	return len(f.allow) == 0 || prefixesContain(f.allow, addr)
+}
+
+// Handler returns the middleware which returns a 403 HTTPError if the client
+// IP, c.ClientIP(), is not allowed. Use it in a GroupRouter or a route.
+// e.g. g.Use(f.Handler())
+func (f *IPFilter) Handler() Handler {
+	return func(c *Context) error {
+		if !f.Allowed(c.ClientIP()) {
+			return NewHTTPError(http.StatusForbidden)
+		}
+		return nil
+	}
+}
+
+// LocalOnly is the guard middleware which only allows the requests from the
+// loopback addresses, c.ClientIP(). It returns a 403 HTTPError otherwise.
+func LocalOnly(c *Context) error {
+	addr := parseNode(c.ClientIP())
+	if !addr.IsValid() || !addr.IsLoopback() {
+		return NewHTTPError(http.StatusForbidden)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package ctx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPFilter(t *testing.T) {
+	_, err := NewIPFilter([]string{"10.0.0.0/x"}, nil)
+	assert.Error(t, err)
+
+	f, err := NewIPFilter([]string{"10.0.0.0/8", "2001:db8::/32"}, []string{"10.0.0.13"})
+	assert.NoError(t, err)
+	assert.True(t, f.Allowed("10.1.2.3"))
+	assert.True(t, f.Allowed("::ffff:10.1.2.3"))
+	assert.True(t, f.Allowed("2001:db8::1"))
+	assert.False(t, f.Allowed("10.0.0.13"))
+	assert.False(t, f.Allowed("192.168.0.1"))
+	assert.False(t, f.Allowed("2001:db9::1"))
+	assert.False(t, f.Allowed("invalid"))
+
+	assert.Error(t, f.Reload(nil, []string{"invalid"}))
+	assert.True(t, f.Allowed("10.1.2.3"))
+	assert.NoError(t, f.Reload(nil, []string{"10.0.0.0/8"}))
+	assert.False(t, f.Allowed("10.1.2.3"))
+	assert.True(t, f.Allowed("192.168.0.1"))
+}
+
+func TestIPFilterHandler(t *testing.T) {
+	resetRouter()
+	f, _ := NewIPFilter([]string{"192.0.2.0/24"}, nil)
+	g := Group("/admin")
+	g.Use(f.Handler())
+	g.GET("/", h)
+	GET("/open", h)
+	GET("/route", h, f.Handler())
+
+	request := func(path, remote string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remote
+		res := httptest.NewRecorder()
+		routerIns.r.ServeHTTP(res, req)
+		return res.Code
+	}
+	assert.Equal(t, 200, request("/admin/", "192.0.2.1:1234"))
+	assert.Equal(t, http.StatusForbidden, request("/admin/", "198.51.100.1:1234"))
+	assert.Equal(t, http.StatusForbidden, request("/route", "198.51.100.1:1234"))
+	assert.Equal(t, 200, request("/open", "198.51.100.1:1234"))
+
+	f.Reload([]string{"198.51.100.0/24"}, nil)
+	assert.Equal(t, 200, request("/admin/", "198.51.100.1:1234"))
+	assert.Equal(t, http.StatusForbidden, request("/admin/", "192.0.2.1:1234"))
+}
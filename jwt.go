@@ -0,0 +1,413 @@
+package ctx
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JWTConfig is the config of JWT.
+type JWTConfig struct {
+	// Key is the key to verify the tokens without `kid`: []byte for HS256,
+	// *rsa.PublicKey for RS256 and *ecdsa.PublicKey for ES256.
+	Key crypto.PublicKey
+	// JWKS is the keys to verify the tokens by `kid`.
+	JWKS *JWKS
+	// Issuer is the required `iss`, empty means not checked.
+	Issuer string
+	// Audience is the required `aud`, empty means not checked.
+	Audience string
+	// Leeway is the allowed clock skew when checking `exp` and `nbf`.
+	Leeway time.Duration
+	// Principal converts the claims to the principal. The default uses `sub`
+	// as ID, `roles` as Roles, and `scope` or `scp` as Scopes.
+	Principal func(claims Map) (*Principal, error)
+}
+
+// JWT returns the middleware which authenticates the Bearer token of
+// Authorization as a JWT signed by HS256, RS256 or ES256. It returns a 401
+// HTTPError with WWW-Authenticate if the authentication fails.
+func JWT(cfg *JWTConfig) Handler {
+	conf := *cfg
+	if conf.Principal == nil {
+		conf.Principal = claimsPrincipal
+	}
+	return func(c *Context) error {
+		auth := c.ReqHeader().Get("Authorization")
+		if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+			c.ResHeader().Set("WWW-Authenticate", "Bearer")
+			return NewHTTPError(http.StatusUnauthorized)
+		}
+		claims, err := conf.Parse(auth[7:])
+		if err != nil {
+			c.ResHeader().Set(
+				"WWW-Authenticate",
+				`Bearer error="invalid_token"`,
+			)
+			return invalidToken(err)
+		}
+		p, err := conf.Principal(claims)
+		if err != nil {
+			return invalidToken(err)
+		}
+		c.SetPrincipal(p)
+		return nil
+	}
+}
+
+// invalidToken logs the reason err of the invalid token and returns a 401
+// HTTPError without it, the reason is not for the clients.
+func invalidToken(err error) error {
+	log.Printf("%s invalid jwt: %v\n", "[ctx]", err)
+	return NewHTTPError(http.StatusUnauthorized, "invalid token")
+}
+
+// jwtHeader is the header of JWT.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Parse verifies the token and returns its claims.
+func (conf *JWTConfig) Parse(token string) (Map, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var header jwtHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	key := conf.Key
+	if header.Kid != "" && conf.JWKS != nil {
+		k, err := conf.JWKS.Key(header.Kid)
+		if err != nil {
+			return nil, err
+		}
+		key = k
+	}
+	if key == nil {
+		return nil, errors.New("unknown key")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+	if err := verifyJWT(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+	var claims Map
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	return claims, conf.validate(claims)
+}
+
+// decodeJWTPart decodes the base64url json part of JWT into v.
+func decodeJWTPart(part string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return errors.New("malformed token")
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return errors.New("malformed token")
+	}
+	return nil
+}
+
+// verifyJWT verifies the signature sig of input with key, the type of key
+// must match alg.
+func verifyJWT(alg string, key crypto.PublicKey, input string, sig []byte) error {
+	sum := sha256.Sum256([]byte(input))
+	switch alg {
+	case "HS256":
+		secret, ok := key.([]byte)
+		if !ok {
+			break
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(input))
+		if hmac.Equal(sig, mac.Sum(nil)) {
+			return nil
+		}
+		return errors.New("invalid signature")
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			break
+		}
+		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig) == nil {
+			return nil
+		}
+		return errors.New("invalid signature")
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != elliptic.P256() {
+			break
+		}
+		if len(sig) == 64 && ecdsa.Verify(
+			pub, sum[:],
+			new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:]),
+		) {
+			return nil
+		}
+		return errors.New("invalid signature")
+	}
+	return fmt.Errorf("unsupported algorithm %q for the key", alg)
+}
+
+// validate checks the registered claims.
+func (conf *JWTConfig) validate(claims Map) error {
+	now := time.Now()
+	if exp, ok := claims["exp"].(float64); ok {
+		if now.After(unixTime(exp).Add(conf.Leeway)) {
+			return errors.New("token expired")
+		}
+	} else if _, ok := claims["exp"]; ok {
+		return errors.New("invalid exp")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok {
+		if now.Add(conf.Leeway).Before(unixTime(nbf)) {
+			return errors.New("token not valid yet")
+		}
+	} else if _, ok := claims["nbf"]; ok {
+		return errors.New("invalid nbf")
+	}
+	if conf.Issuer != "" && claims["iss"] != conf.Issuer {
+		return errors.New("invalid issuer")
+	}
+	if conf.Audience != "" && !claimContains(claims["aud"], conf.Audience) {
+		return errors.New("invalid audience")
+	}
+	return nil
+}
+
+// unixTime converts the NumericDate of JWT to time.
+func unixTime(v float64) time.Time {
+	return time.Unix(0, int64(v*float64(time.Second)))
+}
+
+// claimContains returns if the string or string array claim contains s.
+func claimContains(claim interface{}, s string) bool {
+	for _, v := range claimStrings(claim) {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// claimStrings returns the string or string array claim as a slice.
+func claimStrings(claim interface{}) []string {
+	switch v := claim.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		res := make([]string, 0, len(v))
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+	return nil
+}
+
+// claimsPrincipal is the default JWTConfig.Principal.
+func claimsPrincipal(claims Map) (*Principal, error) {
+	sub, _ := claims["sub"].(string)
+	scopes := claimStrings(claims["scp"])
+	if scope, ok := claims["scope"].(string); ok {
+		scopes = strings.Fields(scope)
+	}
+	return &Principal{
+		ID:     sub,
+		Method: "jwt",
+		Roles:  claimStrings(claims["roles"]),
+		Scopes: scopes,
+		Claims: claims,
+	}, nil
+}
+
+// JWKS is the JSON Web Key Set loaded from URL or File and cached. RSA, EC
+// P-256 and oct keys are supported.
+type JWKS struct {
+	// URL is the url of the JWKS.
+	URL string
+	// File is the path of the JWKS, used if URL is empty.
+	File string
+	// TTL is the time to cache the keys, default is 1 hour. An unknown kid
+	// refreshes the keys at most once a minute.
+	TTL time.Duration
+	// Client is the http client to get URL, default is a client with 10s
+	// timeout.
+	Client *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+	loading chan struct{}
+	err     error
+}
+
+// jwksRefreshInterval is the min interval to refresh JWKS for unknown kids.
+const jwksRefreshInterval = time.Minute
+
+// Key returns the key of kid. The keys are refreshed by one request at a
+// time, the others keep using the cached keys, or wait for the first load.
+func (j *JWKS) Key(kid string) (crypto.PublicKey, error) {
+	ttl := j.TTL
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	j.mu.Lock()
+	key, ok := j.keys[kid]
+	age := time.Since(j.fetched)
+	if j.keys != nil && age <= ttl && (ok || age <= jwksRefreshInterval) {
+		j.mu.Unlock()
+		return jwksKey(kid, key, ok)
+	}
+	if loading := j.loading; loading != nil {
+		if j.keys != nil {
+			j.mu.Unlock()
+			return jwksKey(kid, key, ok)
+		}
+		j.mu.Unlock()
+		<-loading
+		j.mu.Lock()
+	} else {
+		loading = make(chan struct{})
+		j.loading = loading
+		j.fetched = time.Now()
+		j.mu.Unlock()
+		keys, err := j.load()
+		j.mu.Lock()
+		if err == nil {
+			j.keys = keys
+		}
+		j.err = err
+		j.loading = nil
+		close(loading)
+	}
+	defer j.mu.Unlock()
+	if j.keys == nil {
+		return nil, j.err
+	}
+	key, ok = j.keys[kid]
+	return jwksKey(kid, key, ok)
+}
+
+// jwksKey returns key, or the error of the unknown kid if not ok.
+func jwksKey(kid string, key crypto.PublicKey, ok bool) (crypto.PublicKey, error) {
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	return key, nil
+}
+
+// load loads the keys from URL or File.
+func (j *JWKS) load() (map[string]crypto.PublicKey, error) {
+	var r io.Reader
+	if j.URL != "" {
+		client := j.Client
+		if client == nil {
+			client = &http.Client{Timeout: 10 * time.Second}
+		}
+		res, err := client.Get(j.URL)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("get jwks: %s", res.Status)
+		}
+		r = res.Body
+	} else {
+		f, err := os.Open(j.File)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r, 1<<20)).Decode(&set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+// jwk is a JSON Web Key.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// publicKey returns the key of k.
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	b64 := base64.RawURLEncoding
+	switch k.Kty {
+	case "RSA":
+		n, err1 := b64.DecodeString(k.N)
+		e, err2 := b64.DecodeString(k.E)
+		if err1 != nil || err2 != nil || len(e) > 4 {
+			return nil, errors.New("invalid RSA key")
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		x, err1 := b64.DecodeString(k.X)
+		y, err2 := b64.DecodeString(k.Y)
+		if k.Crv != "P-256" || err1 != nil || err2 != nil {
+			return nil, errors.New("invalid EC key")
+		}
+		pub := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New("invalid EC key")
+		}
+		return pub, nil
+	case "oct":
+		secret, err := b64.DecodeString(k.K)
+		if err != nil {
+			return nil, errors.New("invalid oct key")
+		}
+		return secret, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
@@ -0,0 +1,184 @@
+package ctx
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signJWT(t *testing.T, alg, kid string, key interface{}, claims Map) string {
+	header, _ := json.Marshal(Map{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(input))
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(input))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, sum[:])
+		assert.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, sum[:])
+		assert.NoError(t, err)
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestJWTHS256(t *testing.T) {
+	resetRouter()
+	secret := []byte("secret")
+	GET("/", func(c *Context) error {
+		p := c.Principal()
+		return c.Json(Map{"id": p.ID, "roles": p.Roles, "scopes": p.Scopes})
+	}, JWT(&JWTConfig{
+		Key:      secret,
+		Issuer:   "iss",
+		Audience: "api",
+		Leeway:   time.Minute,
+	}))
+	now := time.Now().Unix()
+	claims := Map{
+		"sub": "u1", "iss": "iss", "aud": []string{"web", "api"},
+		"exp": now + 60, "nbf": now + 30,
+		"roles": []string{"admin"}, "scope": "read write",
+	}
+	bearer := func(token string) map[string]string {
+		return map[string]string{"Authorization": "Bearer " + token}
+	}
+
+	res := authRequest("/", bearer(signJWT(t, "HS256", "", secret, claims)))
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(
+		t,
+		`{"id":"u1","roles":["admin"],"scopes":["read","write"]}`,
+		res.Body.String(),
+	)
+
+	res = authRequest("/", nil)
+	assert.Equal(t, 401, res.Code)
+	assert.Equal(t, "Bearer", res.Header().Get("WWW-Authenticate"))
+
+	for k, v := range map[string]interface{}{
+		"exp": now - 61, "nbf": now + 61, "iss": "other", "aud": "web",
+	} {
+		bad := Map{}
+		for ck, cv := range claims {
+			bad[ck] = cv
+		}
+		bad[k] = v
+		res = authRequest("/", bearer(signJWT(t, "HS256", "", secret, bad)))
+		assert.Equal(t, 401, res.Code, k)
+		// the reason is not sent to the client
+		assert.Contains(t, res.Body.String(), "invalid token", k)
+		assert.NotContains(t, res.Body.String(), k, k)
+	}
+	res = authRequest("/", bearer(signJWT(t, "HS256", "", []byte("x"), claims)))
+	assert.Equal(t, 401, res.Code)
+	res = authRequest("/", bearer(signJWT(t, "none", "", secret, claims)))
+	assert.Equal(t, 401, res.Code)
+}
+
+func TestJWTJWKS(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	b64 := base64.RawURLEncoding
+	jwks, _ := json.Marshal(Map{"keys": []Map{
+		{
+			"kty": "RSA", "kid": "rsa",
+			"n": b64.EncodeToString(rsaKey.N.Bytes()),
+			"e": b64.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+		},
+		{
+			"kty": "EC", "kid": "ec", "crv": "P-256",
+			"x": b64.EncodeToString(ecKey.X.Bytes()),
+			"y": b64.EncodeToString(ecKey.Y.Bytes()),
+		},
+	}})
+	fetched := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched++
+		w.Write(jwks)
+	}))
+	defer ts.Close()
+	conf := &JWTConfig{JWKS: &JWKS{URL: ts.URL}}
+
+	claims := Map{"sub": "u1"}
+	got, err := conf.Parse(signJWT(t, "RS256", "rsa", rsaKey, claims))
+	assert.NoError(t, err)
+	assert.Equal(t, "u1", got["sub"])
+	_, err = conf.Parse(signJWT(t, "ES256", "ec", ecKey, claims))
+	assert.NoError(t, err)
+	// the key type must match the algorithm
+	_, err = conf.Parse(signJWT(t, "HS256", "rsa", []byte("x"), claims))
+	assert.Error(t, err)
+	_, err = conf.Parse(signJWT(t, "RS256", "none", rsaKey, claims))
+	assert.Error(t, err)
+	assert.Equal(t, 1, fetched)
+
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(file, jwks, 0600))
+	conf = &JWTConfig{JWKS: &JWKS{File: file}}
+	_, err = conf.Parse(signJWT(t, "ES256", "ec", ecKey, claims))
+	assert.NoError(t, err)
+}
+
+func TestJWKSRefresh(t *testing.T) {
+	release := make(chan struct{})
+	fetched := make(chan struct{}, 2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched <- struct{}{}
+		if len(fetched) > 1 {
+			<-release
+		}
+		w.Write([]byte(`{"keys":[{"kty":"oct","kid":"a","k":"c2VjcmV0"}]}`))
+	}))
+	defer ts.Close()
+	defer close(release)
+	j := &JWKS{URL: ts.URL}
+	_, err := j.Key("a")
+	assert.NoError(t, err)
+
+	// the keys are expired, one request refreshes them and hangs
+	j.mu.Lock()
+	j.fetched = time.Now().Add(-2 * time.Hour)
+	j.mu.Unlock()
+	go j.Key("a")
+	for len(fetched) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	// the others keep using the cached keys without waiting
+	done := make(chan error, 1)
+	go func() {
+		_, err := j.Key("a")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Key waits for the refresh")
+	}
+}
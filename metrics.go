@@ -0,0 +1,322 @@
+package ctx
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultDurationBuckets is the default buckets of the request duration
+// histogram in seconds.
+var DefaultDurationBuckets = []float64{
+	.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10,
+}
+
+// DefaultSizeBuckets is the default buckets of the response size histogram
+// in bytes.
+var DefaultSizeBuckets = []float64{
+	100, 1000, 10000, 100000, 1e6, 1e7, 1e8,
+}
+
+// MetricsConfig is the config of NewMetrics.
+type MetricsConfig struct {
+	// Namespace is the prefix of the metric names, default is "http".
+	Namespace       string
+	DurationBuckets []float64
+	SizeBuckets     []float64
+}
+
+// Metrics records the requests in the Prometheus data model. The requests are
+// labeled by the method, the route pattern and the status class, e.g. "2xx",
+// so the raw paths don't blow up the cardinality.
+type Metrics struct {
+	conf     MetricsConfig
+	inFlight int64
+	// unmatched is if the Handler observes the requests matching no route.
+	unmatched bool
+
+	mu     sync.Mutex
+	series map[metricLabels]*requestSeries
+	errors map[metricLabels]uint64
+	panics map[metricLabels]uint64
+}
+
+// metricLabels is the labels of a series, the errors and the panics have no
+// status.
+type metricLabels struct {
+	method string
+	route  string
+	status string
+}
+
+// requestSeries is the metrics of the requests with the same labels.
+type requestSeries struct {
+	count    uint64
+	duration *histogram
+	size     *histogram
+}
+
+// histogram is a cumulative histogram.
+type histogram struct {
+	bounds []float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// newHistogram returns a histogram with the upper bounds.
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds))}
+}
+
+// observe adds v to the histogram.
+func (h *histogram) observe(v float64) {
+	for i, b := range h.bounds {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// NewMetrics returns a Metrics with cfg, cfg can be nil.
+func NewMetrics(cfg *MetricsConfig) *Metrics {
+	conf := MetricsConfig{}
+	if cfg != nil {
+		conf = *cfg
+	}
+	if conf.Namespace == "" {
+		conf.Namespace = "http"
+	}
+	if len(conf.DurationBuckets) == 0 {
+		conf.DurationBuckets = DefaultDurationBuckets
+	}
+	if len(conf.SizeBuckets) == 0 {
+		conf.SizeBuckets = DefaultSizeBuckets
+	}
+	conf.DurationBuckets = sortedBuckets(conf.DurationBuckets)
+	conf.SizeBuckets = sortedBuckets(conf.SizeBuckets)
+	return &Metrics{
+		conf:   conf,
+		series: make(map[metricLabels]*requestSeries),
+		errors: make(map[metricLabels]uint64),
+		panics: make(map[metricLabels]uint64),
+	}
+}
+
+// sortedBuckets returns a sorted copy of buckets.
+func sortedBuckets(buckets []float64) []float64 {
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	return b
+}
+
+// Handler returns the middleware which records the requests of the routes.
+// The errors passed to ErrorHandler and the panics passed to PanicHandler
+// are counted too. e.g. ctx.Use(m.Handler())
+// The requests matching no route are recorded with the route "<unmatched>"
+// as well, without running the other middleware.
+func (m *Metrics) Handler() Handler {
+	h := Handler(func(c *Context) error {
+		start := time.Now()
+		atomic.AddInt64(&m.inFlight, 1)
+		c.AfterWrite(func() {
+			atomic.AddInt64(&m.inFlight, -1)
+			m.observe(c, time.Since(start))
+		})
+		return nil
+	})
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.unmatched {
+		m.unmatched = true
+		routerIns.unmatched = append(routerIns.unmatched, h)
+	}
+	return h
+}
+
+// unmatchedRoute is the route label of the requests matching no route, the
+// paths are not used as labels, which are unbounded.
+const unmatchedRoute = "<unmatched>"
+
+// metricMethods is the methods used as the method label, the others are
+// "OTHER" since the clients can send any method.
+var metricMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true,
+	http.MethodPut: true, http.MethodPatch: true, http.MethodDelete: true,
+	http.MethodConnect: true, http.MethodOptions: true, http.MethodTrace: true,
+}
+
+// observe records the finished request of c.
+func (m *Metrics) observe(c *Context, d time.Duration) {
+	labels := metricLabels{method: c.Method(), route: unmatchedRoute}
+	if c.route != nil {
+		// the method of a route is registered
+		labels.route = c.route.Path
+	} else if !metricMethods[labels.method] {
+		labels.method = "OTHER"
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c.err != nil {
+		m.errors[labels]++
+	}
+	if c.recovered != nil {
+		m.panics[labels]++
+	}
+	labels.status = strconv.Itoa(c.ResStatus()/100) + "xx"
+	s, ok := m.series[labels]
+	if !ok {
+		s = &requestSeries{
+			duration: newHistogram(m.conf.DurationBuckets),
+			size:     newHistogram(m.conf.SizeBuckets),
+		}
+		m.series[labels] = s
+	}
+	s.count++
+	s.duration.observe(d.Seconds())
+	s.size.observe(float64(c.ResSize()))
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	ns := m.conf.Namespace
+	buf := new(bytes.Buffer)
+	m.mu.Lock()
+	keys := make([]metricLabels, 0, len(m.series))
+	for k := range m.series {
+		keys = append(keys, k)
+	}
+	sortLabels(keys)
+
+	metricHeader(buf, ns+"_requests_total", "counter",
+		"Total number of HTTP requests.")
+	for _, l := range keys {
+		fmt.Fprintf(buf, "%s_requests_total%s %d\n",
+			ns, l.format("", ""), m.series[l].count)
+	}
+
+	metricHeader(buf, ns+"_requests_in_flight", "gauge",
+		"Number of HTTP requests being served.")
+	fmt.Fprintf(buf, "%s_requests_in_flight %d\n",
+		ns, atomic.LoadInt64(&m.inFlight))
+
+	metricHeader(buf, ns+"_request_duration_seconds", "histogram",
+		"HTTP request latencies in seconds.")
+	for _, l := range keys {
+		m.series[l].duration.write(buf, ns+"_request_duration_seconds", l)
+	}
+
+	metricHeader(buf, ns+"_response_size_bytes", "histogram",
+		"HTTP response sizes in bytes.")
+	for _, l := range keys {
+		m.series[l].size.write(buf, ns+"_response_size_bytes", l)
+	}
+
+	metricHeader(buf, ns+"_errors_total", "counter",
+		"Total number of errors passed to ErrorHandler.")
+	for _, l := range counterLabels(m.errors) {
+		fmt.Fprintf(buf, "%s_errors_total%s %d\n",
+			ns, l.format("", ""), m.errors[l])
+	}
+
+	metricHeader(buf, ns+"_panics_total", "counter",
+		"Total number of panics passed to PanicHandler.")
+	for _, l := range counterLabels(m.panics) {
+		fmt.Fprintf(buf, "%s_panics_total%s %d\n",
+			ns, l.format("", ""), m.panics[l])
+	}
+	m.mu.Unlock()
+	return buf.WriteTo(w)
+}
+
+// write writes the series of the histogram with labels l.
+func (h *histogram) write(buf *bytes.Buffer, name string, l metricLabels) {
+	for i, b := range h.bounds {
+		fmt.Fprintf(buf, "%s_bucket%s %d\n",
+			name, l.format("le", formatFloat(b)), h.counts[i])
+	}
+	fmt.Fprintf(buf, "%s_bucket%s %d\n", name, l.format("le", "+Inf"), h.count)
+	fmt.Fprintf(buf, "%s_sum%s %s\n", name, l.format("", ""), formatFloat(h.sum))
+	fmt.Fprintf(buf, "%s_count%s %d\n", name, l.format("", ""), h.count)
+}
+
+// metricHeader writes the HELP and TYPE lines of a metric.
+func metricHeader(buf *bytes.Buffer, name, typ, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// format returns the label set of l in the exposition format, with the
+// extra label k=v if k is not empty.
+func (l metricLabels) format(k, v string) string {
+	pairs := []string{
+		`method="` + escapeLabel(l.method) + `"`,
+		`route="` + escapeLabel(l.route) + `"`,
+	}
+	if l.status != "" {
+		pairs = append(pairs, `status="`+l.status+`"`)
+	}
+	if k != "" {
+		pairs = append(pairs, k+`="`+v+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// labelEscaper escapes the label values.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel escapes the label value s.
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// formatFloat formats f in the exposition format.
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// counterLabels returns the sorted keys of the counter m.
+func counterLabels(m map[metricLabels]uint64) []metricLabels {
+	keys := make([]metricLabels, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return sortLabels(keys)
+}
+
+// sortLabels sorts keys by the route, the method and the status.
+func sortLabels(keys []metricLabels) []metricLabels {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].status < keys[j].status
+	})
+	return keys
+}
+
+// ServeMetrics registers a GET route at path which responses the metrics of
+// m in the Prometheus text exposition format.
+func ServeMetrics(path string, m *Metrics) *Route {
+	return GET(path, func(c *Context) error {
+		c.ResHeader().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		c.SetStatusCode(200)
+		_, err := m.WriteTo(c.Res)
+		return err
+	}).Hidden()
+}
@@ -0,0 +1,71 @@
+package ctx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	resetRouter()
+	m := NewMetrics(&MetricsConfig{Namespace: "app", DurationBuckets: []float64{10, 1}})
+	Use(m.Handler())
+	GET("/users/:id", func(c *Context) error {
+		return c.String("user")
+	})
+	GET("/error", func(c *Context) error {
+		return errors.New("error")
+	})
+	GET("/panic", func(c *Context) error {
+		panic("panic")
+	})
+	ServeMetrics("/metrics", m)
+
+	for _, path := range []string{"/users/1", "/users/2", "/error", "/panic", "/nope", "/.env"} {
+		routerIns.r.ServeHTTP(httptest.NewRecorder(),
+			httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	res := httptest.NewRecorder()
+	routerIns.r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/users/1", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, res.Code)
+	// the made-up methods share a series
+	for _, method := range []string{"X", "XY", "XYY"} {
+		routerIns.r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/nope", nil))
+	}
+	res = httptest.NewRecorder()
+	routerIns.r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", res.Header().Get("Content-Type"))
+	body := res.Body.String()
+	for _, line := range []string{
+		"# TYPE app_requests_total counter",
+		`app_requests_total{method="GET",route="/users/:id",status="2xx"} 2`,
+		`app_requests_total{method="GET",route="/error",status="5xx"} 1`,
+		`app_requests_total{method="GET",route="/panic",status="5xx"} 1`,
+		// the request of /metrics itself
+		"app_requests_in_flight 1",
+		`app_request_duration_seconds_bucket{method="GET",route="/users/:id",status="2xx",le="1"} 2`,
+		`app_request_duration_seconds_bucket{method="GET",route="/users/:id",status="2xx",le="+Inf"} 2`,
+		`app_request_duration_seconds_count{method="GET",route="/users/:id",status="2xx"} 2`,
+		`app_response_size_bytes_bucket{method="GET",route="/users/:id",status="2xx",le="100"} 2`,
+		`app_response_size_bytes_sum{method="GET",route="/users/:id",status="2xx"} 8`,
+		`app_errors_total{method="GET",route="/error"} 1`,
+		`app_panics_total{method="GET",route="/panic"} 1`,
+		// the requests matching no route
+		`app_requests_total{method="GET",route="<unmatched>",status="4xx"} 2`,
+		`app_requests_total{method="POST",route="<unmatched>",status="4xx"} 1`,
+		`app_errors_total{method="GET",route="<unmatched>"} 2`,
+		`app_requests_total{method="OTHER",route="<unmatched>",status="4xx"} 3`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	assert.NotContains(t, body, `route="/users/1"`)
+	assert.NotContains(t, body, `route="/nope"`)
+	assert.NotContains(t, body, `method="XY"`)
+}
+
+func TestEscapeLabel(t *testing.T) {
+	assert.Equal(t, `a\\b\"c\nd`, escapeLabel("a\\b\"c\nd"))
+}
@@ -0,0 +1,318 @@
+package ctx
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// MultipartConfig is the limits of the multipart requests. The zero limits
+// mean no limit.
+type MultipartConfig struct {
+	// MaxMemory is the memory of c.Files to keep the files, the rest is in
+	// the temp files, which are removed after the request.
+	MaxMemory int64
+	// MaxFileSize is the max size of a file.
+	MaxFileSize int64
+	// MaxTotalSize is the max size of the request body.
+	MaxTotalSize int64
+	// AllowedTypes is the allowed MIME types of the files, sniffed from the
+	// content rather than the declared Content-Type, e.g. "image/png" or
+	// "image/*". Empty means all.
+	AllowedTypes []string
+}
+
+// DefaultMultipartConfig is the config used if the cfg is nil.
+var DefaultMultipartConfig = &MultipartConfig{
+	MaxMemory:    10 << 20,
+	MaxFileSize:  32 << 20,
+	MaxTotalSize: 128 << 20,
+}
+
+// allowed returns if the MIME type ctype is allowed.
+func (conf *MultipartConfig) allowed(ctype string) bool {
+	if len(conf.AllowedTypes) == 0 {
+		return true
+	}
+	ctype, _, _ = mime.ParseMediaType(ctype)
+	for _, t := range conf.AllowedTypes {
+		if t == ctype || t == "*/*" ||
+			(strings.HasSuffix(t, "/*") && strings.HasPrefix(ctype, t[:len(t)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// limitBody limits the size of the request body by MaxTotalSize.
+func (c *Context) limitBody(conf *MultipartConfig) {
+	if conf.MaxTotalSize > 0 {
+		c.Req.Body = http.MaxBytesReader(c.Res, c.Req.Body, conf.MaxTotalSize)
+	}
+}
+
+// multipartError converts the error of reading the multipart body into a
+// HTTPError.
+func multipartError(err error) error {
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return NewHTTPError(http.StatusRequestEntityTooLarge)
+	}
+	if errors.Is(err, http.ErrNotMultipart) ||
+		errors.Is(err, http.ErrMissingBoundary) {
+		return NewHTTPError(http.StatusUnsupportedMediaType)
+	}
+	return NewHTTPError(http.StatusBadRequest, "invalid multipart body")
+}
+
+// formError converts the error of parsing the form body into a HTTPError.
+func formError(err error) error {
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return NewHTTPError(http.StatusRequestEntityTooLarge)
+	}
+	return NewHTTPError(http.StatusBadRequest, "invalid form body")
+}
+
+// sniff returns the MIME type of the content of r, then r can be read from
+// the start again.
+func sniff(r io.Reader) (string, *bufio.Reader) {
+	br := bufio.NewReaderSize(r, 512)
+	head, _ := br.Peek(512)
+	return http.DetectContentType(head), br
+}
+
+// Files returns the files of the form field key, with the limits of cfg,
+// cfg is DefaultMultipartConfig if nil. The form is parsed in the first call,
+// so the total size and the memory limits are from the first cfg. It returns
+// a 413 HTTPError if a file is too large, and a 415 HTTPError if a file is
+// not allowed.
+func (c *Context) Files(key string, cfg *MultipartConfig) ([]*multipart.FileHeader, error) {
+	if cfg == nil {
+		cfg = DefaultMultipartConfig
+	}
+	if c.Req.MultipartForm == nil {
+		c.limitBody(cfg)
+		if err := c.Req.ParseMultipartForm(cfg.MaxMemory); err != nil {
+			return nil, multipartError(err)
+		}
+	}
+	files := c.Req.MultipartForm.File[key]
+	for _, fh := range files {
+		if cfg.MaxFileSize > 0 && fh.Size > cfg.MaxFileSize {
+			return nil, NewHTTPError(http.StatusRequestEntityTooLarge)
+		}
+		if len(cfg.AllowedTypes) == 0 {
+			continue
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return nil, e("open multipart file error", err)
+		}
+		ctype, _ := sniff(f)
+		f.Close()
+		if !cfg.allowed(ctype) {
+			return nil, NewHTTPError(http.StatusUnsupportedMediaType)
+		}
+	}
+	return files, nil
+}
+
+// SaveFile saves the file fh into the directory dir, the name is the
+// sanitized filename of fh, with a random suffix if the name exists. It
+// returns the path of the saved file.
+func (c *Context) SaveFile(fh *multipart.FileHeader, dir string) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", e("save file error", err)
+	}
+	defer f.Close()
+	return saveFile(f, dir, fh.Filename)
+}
+
+// saveFile saves r into dir with the sanitized name, the existing files are
+// never overwritten.
+func saveFile(r io.Reader, dir, name string) (string, error) {
+	dst, err := createFile(dir, SanitizeFilename(name))
+	if err != nil {
+		return "", e("save file error", err)
+	}
+	path := dst.Name()
+	if _, err := io.Copy(dst, r); err != nil {
+		dst.Close()
+		os.Remove(path)
+		var he *HTTPError
+		if errors.As(err, &he) {
+			return "", he
+		}
+		return "", e("save file error", err)
+	}
+	if err := dst.Close(); err != nil {
+		return "", e("save file error", err)
+	}
+	return path, nil
+}
+
+// createFile creates a new file named name in dir. If name exists, a random
+// suffix is added before the extension, e.g. "a-1f2e3d4c.png".
+func createFile(dir, name string) (*os.File, error) {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for i := 0; ; i++ {
+		path := filepath.Join(dir, name)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if !os.IsExist(err) || i == 10 {
+			return f, err
+		}
+		suffix := make([]byte, 4)
+		rand.Read(suffix)
+		name = stem + "-" + hex.EncodeToString(suffix) + ext
+	}
+}
+
+// maxFilenameLen is the max length of a sanitized filename in bytes.
+const maxFilenameLen = 200
+
+// windowsReserved is the reserved file names of Windows.
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// SanitizeFilename returns a safe file name of the client provided name. The
+// directories are removed, the characters other than the letters, the digits,
+// '.', '-' and '_' are replaced by '_', and the leading dots are removed.
+// It's "file" if nothing left.
+func SanitizeFilename(name string) string {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) ||
+			r == '.' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	name = strings.TrimLeft(name, ".")
+	if len(name) > maxFilenameLen {
+		ext := filepath.Ext(name)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		base := strings.ToValidUTF8(name[:maxFilenameLen-len(ext)], "")
+		name = base + ext
+	}
+	if stem, _, _ := strings.Cut(name, "."); windowsReserved[strings.ToUpper(stem)] {
+		name = "_" + name
+	}
+	if name == "" {
+		return "file"
+	}
+	return name
+}
+
+// MultipartStream reads the parts of a multipart request one by one without
+// buffering, see c.MultipartStream.
+type MultipartStream struct {
+	r    *multipart.Reader
+	conf *MultipartConfig
+}
+
+// MultipartStream returns the streaming reader of the multipart request
+// body, with the limits of cfg, cfg is DefaultMultipartConfig if nil. The
+// MaxMemory is not used.
+func (c *Context) MultipartStream(cfg *MultipartConfig) (*MultipartStream, error) {
+	if cfg == nil {
+		cfg = DefaultMultipartConfig
+	}
+	c.limitBody(cfg)
+	r, err := c.Req.MultipartReader()
+	if err != nil {
+		return nil, multipartError(err)
+	}
+	return &MultipartStream{r: r, conf: cfg}, nil
+}
+
+// MultipartPart is a form field or a file in the multipart request. Read it
+// before the next part.
+type MultipartPart struct {
+	// FormName is the name of the form field.
+	FormName string
+	// FileName is the file name provided by the client, "" if it's not a
+	// file. Use SanitizeFilename before using it as a path.
+	FileName string
+	// ContentType is the sniffed MIME type of a file, or the declared one of
+	// a field.
+	ContentType string
+	Header      textproto.MIMEHeader
+
+	r    io.Reader
+	max  int64
+	read int64
+}
+
+// NextPart returns the next part, io.EOF if no more part. It returns a 413
+// HTTPError if the body is too large, and a 415 HTTPError if the file is not
+// allowed.
+func (s *MultipartStream) NextPart() (*MultipartPart, error) {
+	p, err := s.r.NextPart()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, multipartError(err)
+	}
+	part := &MultipartPart{
+		FormName:    p.FormName(),
+		FileName:    p.FileName(),
+		ContentType: p.Header.Get("Content-Type"),
+		Header:      p.Header,
+		r:           p,
+	}
+	if part.FileName != "" {
+		part.max = s.conf.MaxFileSize
+		ctype, br := sniff(p)
+		part.ContentType, part.r = ctype, br
+		if !s.conf.allowed(ctype) {
+			return nil, NewHTTPError(http.StatusUnsupportedMediaType)
+		}
+	}
+	return part, nil
+}
+
+// Read implements the io.Reader interface. It returns a 413 HTTPError if the
+// file or the body is too large.
+func (p *MultipartPart) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	if over := p.read - p.max; p.max > 0 && over > 0 {
+		if over > int64(n) {
+			over = int64(n)
+		}
+		return n - int(over), NewHTTPError(http.StatusRequestEntityTooLarge)
+	}
+	if err != nil && err != io.EOF {
+		return n, multipartError(err)
+	}
+	return n, err
+}
+
+// SaveTo saves the file part into the directory dir, the name is the
+// sanitized FileName. It returns the path of the saved file.
+func (p *MultipartPart) SaveTo(dir string) (string, error) {
+	return saveFile(p, dir, p.FileName)
+}
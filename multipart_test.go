@@ -0,0 +1,188 @@
+package ctx
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n0000")
+
+// multipartRequest returns a multipart request with the field "name" and the
+// files of the field "files".
+func multipartRequest(files map[string][]byte) *http.Request {
+	body := new(bytes.Buffer)
+	w := multipart.NewWriter(body)
+	w.WriteField("name", "value")
+	for name, content := range files {
+		fw, _ := w.CreateFormFile("files", name)
+		fw.Write(content)
+	}
+	w.Close()
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	c := getContext(httptest.NewRecorder(), multipartRequest(map[string][]byte{
+		"../../a.png": pngHeader,
+		"b.txt":       []byte("hello"),
+	}))
+	files, err := c.Files("files", nil)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	for _, fh := range files {
+		path, err := c.SaveFile(fh, dir)
+		assert.NoError(t, err)
+		assert.Equal(t, dir, filepath.Dir(path))
+	}
+	b, _ := os.ReadFile(filepath.Join(dir, "a.png"))
+	assert.Equal(t, pngHeader, b)
+
+	// the existing files are not overwritten
+	c = getContext(httptest.NewRecorder(), multipartRequest(map[string][]byte{
+		"a.png": []byte("other"),
+	}))
+	files, err = c.Files("files", nil)
+	assert.NoError(t, err)
+	path, err := c.SaveFile(files[0], dir)
+	assert.NoError(t, err)
+	assert.Regexp(t, `^a-[0-9a-f]{8}\.png$`, filepath.Base(path))
+	b, _ = os.ReadFile(path)
+	assert.Equal(t, "other", string(b))
+	b, _ = os.ReadFile(filepath.Join(dir, "a.png"))
+	assert.Equal(t, pngHeader, b)
+
+	c = getContext(httptest.NewRecorder(), multipartRequest(map[string][]byte{
+		"a.png": pngHeader, "b.txt": []byte("hello"),
+	}))
+	_, err = c.Files("files", &MultipartConfig{AllowedTypes: []string{"image/*"}})
+	assert.Equal(t, http.StatusUnsupportedMediaType, err.(*HTTPError).Code)
+
+	c = getContext(httptest.NewRecorder(), multipartRequest(map[string][]byte{
+		"b.txt": []byte("hello"),
+	}))
+	_, err = c.Files("files", &MultipartConfig{MaxFileSize: 4})
+	assert.Equal(t, http.StatusRequestEntityTooLarge, err.(*HTTPError).Code)
+
+	c = getContext(httptest.NewRecorder(), multipartRequest(map[string][]byte{
+		"b.txt": bytes.Repeat([]byte("a"), 1024),
+	}))
+	_, err = c.Files("files", &MultipartConfig{MaxTotalSize: 512})
+	assert.Equal(t, http.StatusRequestEntityTooLarge, err.(*HTTPError).Code)
+
+	c = getContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+	_, err = c.Files("files", nil)
+	assert.Equal(t, http.StatusUnsupportedMediaType, err.(*HTTPError).Code)
+}
+
+func TestFile(t *testing.T) {
+	c := getContext(httptest.NewRecorder(), multipartRequest(map[string][]byte{
+		"b.txt": []byte("hello"),
+	}))
+	f, fh, err := c.File("files")
+	assert.NoError(t, err)
+	assert.Equal(t, "b.txt", fh.Filename)
+	b, _ := io.ReadAll(f)
+	f.Close()
+	assert.Equal(t, "hello", string(b))
+	_, _, err = c.File("none")
+	assert.Equal(t, http.ErrMissingFile, err)
+
+	defer func(conf MultipartConfig) {
+		*DefaultMultipartConfig = conf
+	}(*DefaultMultipartConfig)
+	DefaultMultipartConfig.MaxTotalSize = 512
+	c = getContext(httptest.NewRecorder(), multipartRequest(map[string][]byte{
+		"b.txt": bytes.Repeat([]byte("a"), 1024),
+	}))
+	_, _, err = c.File("files")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, err.(*HTTPError).Code)
+}
+
+func TestMultipartTempFiles(t *testing.T) {
+	var form *multipart.Form
+	req := multipartRequest(map[string][]byte{"a.txt": []byte("hello")})
+	Handler(func(c *Context) error {
+		_, err := c.Files("files", &MultipartConfig{MaxMemory: 1})
+		form = c.Req.MultipartForm
+		return err
+	}).ServeHTTP(httptest.NewRecorder(), req)
+	f, err := form.File["files"][0].Open()
+	if err == nil {
+		f.Close()
+	}
+	assert.Error(t, err)
+}
+
+func TestMultipartStream(t *testing.T) {
+	dir := t.TempDir()
+	c := getContext(httptest.NewRecorder(), multipartRequest(map[string][]byte{
+		"a.png": pngHeader,
+	}))
+	s, err := c.MultipartStream(&MultipartConfig{AllowedTypes: []string{"image/png"}})
+	assert.NoError(t, err)
+	part, err := s.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, "name", part.FormName)
+	b, _ := io.ReadAll(part)
+	assert.Equal(t, "value", string(b))
+	part, err = s.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, "a.png", part.FileName)
+	assert.Equal(t, "image/png", part.ContentType)
+	path, err := part.SaveTo(dir)
+	assert.NoError(t, err)
+	b, _ = os.ReadFile(path)
+	assert.Equal(t, pngHeader, b)
+	_, err = s.NextPart()
+	assert.Equal(t, io.EOF, err)
+
+	c = getContext(httptest.NewRecorder(), multipartRequest(map[string][]byte{
+		"b.txt": []byte("hello"),
+	}))
+	s, _ = c.MultipartStream(&MultipartConfig{AllowedTypes: []string{"image/png"}})
+	s.NextPart()
+	_, err = s.NextPart()
+	assert.Equal(t, http.StatusUnsupportedMediaType, err.(*HTTPError).Code)
+
+	c = getContext(httptest.NewRecorder(), multipartRequest(map[string][]byte{
+		"b.txt": bytes.Repeat([]byte("a"), 1024),
+	}))
+	s, _ = c.MultipartStream(&MultipartConfig{MaxFileSize: 100})
+	s.NextPart()
+	part, _ = s.NextPart()
+	_, err = part.SaveTo(dir)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, err.(*HTTPError).Code)
+	_, err = os.Stat(filepath.Join(dir, "b.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	for name, expected := range map[string]string{
+		"a.png":              "a.png",
+		"../../etc/passwd":   "passwd",
+		`C:\Users\x\a b.txt`: "a_b.txt",
+		"..":                 "file",
+		".hidden":            "hidden",
+		"":                   "file",
+		"con.txt":            "_con.txt",
+		"图片.png":             "图片.png",
+		"a\x00b;rm -rf.sh":   "a_b_rm_-rf.sh",
+	} {
+		assert.Equal(t, expected, SanitizeFilename(name), name)
+	}
+	long := SanitizeFilename(strings.Repeat("a", 300) + ".png")
+	assert.Len(t, long, maxFilenameLen)
+	assert.True(t, strings.HasSuffix(long, ".png"))
+}
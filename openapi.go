@@ -0,0 +1,345 @@
+package ctx
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAPIConfig is the document level settings of the OpenAPI document.
+type OpenAPIConfig struct {
+	Title       string
+	Version     string
+	Description string
+	// Servers is the base urls of the api.
+	Servers []string
+	// SecuritySchemes is the OpenAPI security scheme objects by name, e.g.
+	// Map{"bearer": Map{"type": "http", "scheme": "bearer"}}.
+	SecuritySchemes Map
+}
+
+// routeDoc is the OpenAPI description of a route.
+type routeDoc struct {
+	hidden   bool
+	reads    reflect.Type
+	writes   map[int]reflect.Type
+	security []string
+}
+
+// Doc sets the summary and the tags of the route in the OpenAPI document.
+func (rt *Route) Doc(summary string, tags ...string) *Route {
+	rt.Summary = summary
+	rt.Tags = tags
+	return rt
+}
+
+// Reads sets the type of the request of the route. It's the query parameters
+// for GET, HEAD and DELETE requests, and the json body for the others.
+// Query parameters are named by the `query` tag or the `json` tag.
+func (rt *Route) Reads(v interface{}) *Route {
+	rt.doc.reads = reflect.TypeOf(v)
+	return rt
+}
+
+// Writes sets the type of the json response with status code `code`. v can
+// be nil if the response has no body.
+func (rt *Route) Writes(code int, v interface{}) *Route {
+	if rt.doc.writes == nil {
+		rt.doc.writes = make(map[int]reflect.Type)
+	}
+	rt.doc.writes[code] = reflect.TypeOf(v)
+	return rt
+}
+
+// Security sets the names of the security schemes required by the route.
+func (rt *Route) Security(schemes ...string) *Route {
+	rt.doc.security = schemes
+	return rt
+}
+
+// Hidden excludes the route from the OpenAPI document.
+func (rt *Route) Hidden() *Route {
+	rt.doc.hidden = true
+	return rt
+}
+
+// ServeOpenAPI registers a GET route at path which responses the OpenAPI
+// document of all the other routes.
+func ServeOpenAPI(path string, cfg *OpenAPIConfig) *Route {
+	return GET(path, func(c *Context) error {
+		return c.Json(OpenAPI(cfg))
+	}).Hidden()
+}
+
+// OpenAPI returns the OpenAPI 3 document of the registered routes.
+func OpenAPI(cfg *OpenAPIConfig) Map {
+	if cfg == nil {
+		cfg = new(OpenAPIConfig)
+	}
+	s := &schemas{defs: make(Map), seen: make(map[reflect.Type]string)}
+	paths := make(Map)
+	for _, rt := range Routes() {
+		if rt.doc.hidden {
+			continue
+		}
+		path, params := openAPIPath(rt.Path)
+		item, ok := paths[path].(Map)
+		if !ok {
+			item = make(Map)
+			paths[path] = item
+		}
+		item[strings.ToLower(rt.Method)] = s.operation(&rt, params)
+	}
+	info := Map{"title": cfg.Title, "version": cfg.Version}
+	if cfg.Description != "" {
+		info["description"] = cfg.Description
+	}
+	doc := Map{
+		"openapi": "3.0.3",
+		"info":    info,
+		"paths":   paths,
+	}
+	if len(cfg.Servers) != 0 {
+		servers := make([]Map, 0, len(cfg.Servers))
+		for _, u := range cfg.Servers {
+			servers = append(servers, Map{"url": u})
+		}
+		doc["servers"] = servers
+	}
+	components := make(Map)
+	if len(s.defs) != 0 {
+		components["schemas"] = s.defs
+	}
+	if len(cfg.SecuritySchemes) != 0 {
+		components["securitySchemes"] = cfg.SecuritySchemes
+	}
+	if len(components) != 0 {
+		doc["components"] = components
+	}
+	return doc
+}
+
+// openAPIPath converts the httprouter path into the OpenAPI path, and returns
+// the names of the path parameters.
+func openAPIPath(path string) (string, []string) {
+	segments := strings.Split(path, "/")
+	params := []string{}
+	for i, s := range segments {
+		if len(s) != 0 && (s[0] == ':' || s[0] == '*') {
+			params = append(params, s[1:])
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// schemas collects the json schemas of the named struct types.
+type schemas struct {
+	defs Map
+	seen map[reflect.Type]string
+}
+
+// operation returns the OpenAPI operation object of rt.
+func (s *schemas) operation(rt *Route, pathParams []string) Map {
+	op := Map{}
+	if rt.Name != "" {
+		op["operationId"] = rt.Name
+	}
+	if rt.Summary != "" {
+		op["summary"] = rt.Summary
+	}
+	if len(rt.Tags) != 0 {
+		op["tags"] = rt.Tags
+	}
+	params := []Map{}
+	for _, p := range pathParams {
+		params = append(params, Map{
+			"name":     p,
+			"in":       "path",
+			"required": true,
+			"schema":   Map{"type": "string"},
+		})
+	}
+	if t := rt.doc.reads; t != nil {
+		switch rt.Method {
+		case "GET", "HEAD", "DELETE":
+			params = append(params, s.queryParams(t)...)
+		default:
+			op["requestBody"] = Map{
+				"required": true,
+				"content": Map{
+					"application/json": Map{"schema": s.schema(t)},
+				},
+			}
+		}
+	}
+	if len(params) != 0 {
+		op["parameters"] = params
+	}
+	responses := Map{}
+	for code, t := range rt.doc.writes {
+		res := Map{"description": http.StatusText(code)}
+		if t != nil {
+			res["content"] = Map{
+				"application/json": Map{"schema": s.schema(t)},
+			}
+		}
+		responses[strconv.Itoa(code)] = res
+	}
+	if len(responses) == 0 {
+		responses["200"] = Map{"description": "OK"}
+	}
+	op["responses"] = responses
+	if len(rt.doc.security) != 0 {
+		security := make([]Map, 0, len(rt.doc.security))
+		for _, name := range rt.doc.security {
+			security = append(security, Map{name: []string{}})
+		}
+		op["security"] = security
+	}
+	return op
+}
+
+// queryParams returns the query parameters of the fields of struct t.
+func (s *schemas) queryParams(t reflect.Type) []Map {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	params := []Map{}
+	if t.Kind() != reflect.Struct {
+		return params
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _ := fieldName(f, "query")
+		if name == "" {
+			continue
+		}
+		p := Map{
+			"name":   name,
+			"in":     "query",
+			"schema": s.schema(f.Type),
+		}
+		if d := f.Tag.Get("description"); d != "" {
+			p["description"] = d
+		}
+		params = append(params, p)
+	}
+	return params
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema returns the json schema of t, named struct types are referenced from
+// the components.
+func (s *schemas) schema(t reflect.Type) Map {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return Map{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		name, ok := s.seen[t]
+		if !ok {
+			name = t.Name()
+			for i := 2; s.defs[name] != nil; i++ {
+				name = t.Name() + strconv.Itoa(i)
+			}
+			s.seen[t] = name
+			// placeholder for recursive types
+			s.defs[name] = Map{}
+			s.defs[name] = s.object(t)
+		}
+		return Map{"$ref": "#/components/schemas/" + name}
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		return s.object(t)
+	case reflect.Bool:
+		return Map{"type": "boolean"}
+	case reflect.Int, reflect.Uint:
+		return Map{"type": "integer"}
+	case reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return Map{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return Map{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return Map{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return Map{"type": "number", "format": "double"}
+	case reflect.String:
+		return Map{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Map{"type": "string", "format": "byte"}
+		}
+		return Map{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return Map{
+			"type":                 "object",
+			"additionalProperties": s.schema(t.Elem()),
+		}
+	}
+	return Map{}
+}
+
+// object returns the json schema of the struct t.
+func (s *schemas) object(t reflect.Type) Map {
+	props := Map{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, omitempty := fieldName(f, "json")
+		if name == "" {
+			continue
+		}
+		p := s.schema(f.Type)
+		if d := f.Tag.Get("description"); d != "" {
+			if _, ok := p["$ref"]; ok {
+				p = Map{"allOf": []Map{p}}
+			}
+			p["description"] = d
+		}
+		props[name] = p
+		if !omitempty && f.Type.Kind() != reflect.Ptr {
+			required = append(required, name)
+		}
+	}
+	o := Map{"type": "object", "properties": props}
+	if len(required) != 0 {
+		sort.Strings(required)
+		o["required"] = required
+	}
+	return o
+}
+
+// fieldName returns the name of struct field f from the tag `key` or the json
+// tag, and if it's omitempty. It returns "" if the field is skipped.
+func fieldName(f reflect.StructField, key string) (string, bool) {
+	if f.PkgPath != "" {
+		return "", false
+	}
+	tag, ok := f.Tag.Lookup(key)
+	if !ok {
+		tag = f.Tag.Get("json")
+	}
+	if tag == "-" {
+		return "", false
+	}
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = f.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			return name, true
+		}
+	}
+	return name, false
+}
@@ -0,0 +1,77 @@
+package ctx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testUser struct {
+	ID      int64      `json:"id"`
+	Name    string     `json:"name" description:"user name"`
+	Friends []testUser `json:"friends,omitempty"`
+	Created time.Time  `json:"created"`
+	secret  string
+}
+
+type testUserQuery struct {
+	Page int    `query:"page"`
+	Sort string `json:"sort"`
+	Skip bool   `json:"-"`
+}
+
+func TestOpenAPI(t *testing.T) {
+	resetRouter()
+	GET("/users/:id", h).Named("getUser").
+		Doc("get a user", "user").
+		Writes(200, testUser{}).
+		Writes(404, nil).
+		Security("bearer")
+	GET("/users", h).Reads(testUserQuery{}).Writes(200, []testUser{})
+	POST("/users", h).Reads(&testUser{})
+	ServeOpenAPI("/openapi.json", &OpenAPIConfig{
+		Title:   "test",
+		Version: "1.0",
+		SecuritySchemes: Map{
+			"bearer": Map{"type": "http", "scheme": "bearer"},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	res := httptest.NewRecorder()
+	routerIns.r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	doc := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &doc))
+	paths := doc["paths"].(map[string]interface{})
+	assert.Len(t, paths, 2)
+	assert.NotContains(t, paths, "/openapi.json")
+
+	get := paths["/users/{id}"].(map[string]interface{})["get"].(map[string]interface{})
+	assert.Equal(t, "getUser", get["operationId"])
+	assert.Equal(t, "get a user", get["summary"])
+	assert.Equal(t, []interface{}{"user"}, get["tags"])
+	assert.Equal(t, "path", get["parameters"].([]interface{})[0].(map[string]interface{})["in"])
+	assert.Contains(t, get["responses"], "404")
+	assert.Equal(t, []interface{}{map[string]interface{}{"bearer": []interface{}{}}}, get["security"])
+
+	list := paths["/users"].(map[string]interface{})["get"].(map[string]interface{})
+	params := list["parameters"].([]interface{})
+	assert.Len(t, params, 2)
+	assert.Equal(t, "page", params[0].(map[string]interface{})["name"])
+	assert.Equal(t, "sort", params[1].(map[string]interface{})["name"])
+
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	user := schemas["testUser"].(map[string]interface{})
+	props := user["properties"].(map[string]interface{})
+	assert.Len(t, props, 4)
+	assert.Equal(t, map[string]interface{}{"type": "integer", "format": "int64"}, props["id"])
+	assert.Equal(t, "user name", props["name"].(map[string]interface{})["description"])
+	assert.Equal(t, "date-time", props["created"].(map[string]interface{})["format"])
+	assert.Equal(t, []interface{}{"created", "id", "name"}, user["required"])
+	assert.Contains(t, doc["components"], "securitySchemes")
+}
@@ -0,0 +1,258 @@
+package ctx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// OTLPExporter exports the spans to an OpenTelemetry collector with OTLP/HTTP
+// in JSON. The spans are sent in batches in background.
+type OTLPExporter struct {
+	// Endpoint is the traces endpoint of the collector, default is
+	// "http://localhost:4318/v1/traces".
+	Endpoint    string
+	ServiceName string
+	// Header is the extra headers of the export requests, e.g. the
+	// authorization of the collector.
+	Header http.Header
+	// Client is the client of the export requests, a client with a 10s
+	// timeout if nil.
+	Client *http.Client
+	// BatchSize is the max number of spans in a batch, default is 512.
+	BatchSize int
+	// Interval is the max delay of a span to be sent, default is 5s.
+	Interval time.Duration
+	// MaxQueueSize is the max number of the queued spans, the spans are
+	// dropped if the queue is full, e.g. the collector is down. Default is
+	// 4 times of BatchSize.
+	MaxQueueSize int
+
+	mu       sync.Mutex
+	queue    []*Span
+	timer    *time.Timer
+	flushing bool
+	dropped  uint64
+}
+
+// otlpClient is the default client of OTLPExporter.
+var otlpClient = &http.Client{Timeout: 10 * time.Second}
+
+// batchSize returns the batch size, default is 512.
+func (ex *OTLPExporter) batchSize() int {
+	if ex.BatchSize <= 0 {
+		return 512
+	}
+	return ex.BatchSize
+}
+
+// Export implements the SpanExporter interface, it queues s to send.
+func (ex *OTLPExporter) Export(s *Span) error {
+	maxQueueSize := ex.MaxQueueSize
+	if maxQueueSize <= 0 {
+		maxQueueSize = 4 * ex.batchSize()
+	}
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+	if len(ex.queue) >= maxQueueSize {
+		ex.dropped++
+		return nil
+	}
+	ex.queue = append(ex.queue, s)
+	ex.schedule()
+	return nil
+}
+
+// Dropped returns the number of the spans dropped since the queue is full.
+func (ex *OTLPExporter) Dropped() uint64 {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+	return ex.dropped
+}
+
+// schedule starts the background flush if the batch is full, or starts the
+// timer of Interval. At most one background flush runs at a time. ex.mu must
+// be held.
+func (ex *OTLPExporter) schedule() {
+	if ex.flushing || len(ex.queue) == 0 {
+		return
+	}
+	if len(ex.queue) >= ex.batchSize() {
+		if ex.timer != nil {
+			ex.timer.Stop()
+			ex.timer = nil
+		}
+		ex.flushing = true
+		go ex.flushAsync()
+		return
+	}
+	if ex.timer == nil {
+		interval := ex.Interval
+		if interval <= 0 {
+			interval = 5 * time.Second
+		}
+		ex.timer = time.AfterFunc(interval, func() {
+			ex.mu.Lock()
+			defer ex.mu.Unlock()
+			ex.timer = nil
+			if !ex.flushing && len(ex.queue) != 0 {
+				ex.flushing = true
+				go ex.flushAsync()
+			}
+		})
+	}
+}
+
+// flushAsync flushes the queue in background and logs the error.
+func (ex *OTLPExporter) flushAsync() {
+	if err := ex.Flush(); err != nil {
+		log.Printf("%s otlp export error: %v\n", "[ctx]", err)
+	}
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+	ex.flushing = false
+	ex.schedule()
+}
+
+// Flush sends the queued spans now. Call it before the app exits.
+func (ex *OTLPExporter) Flush() error {
+	ex.mu.Lock()
+	spans := ex.queue
+	ex.queue = nil
+	if ex.timer != nil {
+		ex.timer.Stop()
+		ex.timer = nil
+	}
+	ex.mu.Unlock()
+	batchSize := ex.batchSize()
+	for len(spans) != 0 {
+		n := len(spans)
+		if n > batchSize {
+			n = batchSize
+		}
+		if err := ex.send(spans[:n]); err != nil {
+			return err
+		}
+		spans = spans[n:]
+	}
+	return nil
+}
+
+// send sends a batch of spans.
+func (ex *OTLPExporter) send(spans []*Span) error {
+	b, err := json.Marshal(otlpTraces(ex.ServiceName, spans))
+	if err != nil {
+		return e("otlp export error", err)
+	}
+	endpoint := ex.Endpoint
+	if endpoint == "" {
+		endpoint = "http://localhost:4318/v1/traces"
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(b))
+	if err != nil {
+		return e("otlp export error", err)
+	}
+	for k, v := range ex.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := ex.Client
+	if client == nil {
+		client = otlpClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return e("otlp export error", err)
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+	if res.StatusCode/100 != 2 {
+		return e("otlp export error", fmt.Errorf("status %s", res.Status))
+	}
+	return nil
+}
+
+// otlpTraces returns the ExportTraceServiceRequest of spans in the OTLP JSON
+// encoding, the ids are hex and the 64 bit integers are strings.
+func otlpTraces(serviceName string, spans []*Span) Map {
+	list := make([]Map, 0, len(spans))
+	for _, s := range spans {
+		s.mu.Lock()
+		span := Map{
+			"traceId":           s.TraceID.String(),
+			"spanId":            s.SpanID.String(),
+			"name":              s.Name,
+			"kind":              2, // SPAN_KIND_SERVER
+			"startTimeUnixNano": strconv.FormatInt(s.Start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.End.UnixNano(), 10),
+			"attributes":        otlpAttributes(s.Attributes),
+		}
+		if s.ParentID.IsValid() {
+			span["parentSpanId"] = s.ParentID.String()
+		}
+		if s.TraceState != "" {
+			span["traceState"] = s.TraceState
+		}
+		if s.Error != "" {
+			span["status"] = Map{"code": 2, "message": s.Error} // STATUS_CODE_ERROR
+		}
+		events := make([]Map, 0, len(s.Events))
+		for _, ev := range s.Events {
+			events = append(events, Map{
+				"name":         ev.Name,
+				"timeUnixNano": strconv.FormatInt(ev.Time.UnixNano(), 10),
+				"attributes":   otlpAttributes(ev.Attributes),
+			})
+		}
+		span["events"] = events
+		s.mu.Unlock()
+		list = append(list, span)
+	}
+	resource := Map{"attributes": otlpAttributes(Map{"service.name": serviceName})}
+	return Map{"resourceSpans": []Map{{
+		"resource": resource,
+		"scopeSpans": []Map{{
+			"scope": Map{"name": "github.com/BouncyElf/ctx"},
+			"spans": list,
+		}},
+	}}}
+}
+
+// otlpAttributes returns attrs as the OTLP KeyValue list sorted by key.
+func otlpAttributes(attrs Map) []Map {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	list := make([]Map, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, Map{"key": k, "value": otlpValue(attrs[k])})
+	}
+	return list
+}
+
+// otlpValue returns v as the OTLP AnyValue.
+func otlpValue(v interface{}) Map {
+	switch v := v.(type) {
+	case bool:
+		return Map{"boolValue": v}
+	case int:
+		return Map{"intValue": strconv.Itoa(v)}
+	case int64:
+		return Map{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		return Map{"doubleValue": v}
+	case string:
+		return Map{"stringValue": v}
+	default:
+		return Map{"stringValue": fmt.Sprint(v)}
+	}
+}
@@ -0,0 +1,126 @@
+package ctx
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "token", r.Header.Get("Authorization"))
+		b, _ := io.ReadAll(r.Body)
+		bodies <- b
+	}))
+	defer collector.Close()
+
+	ex := &OTLPExporter{
+		Endpoint:    collector.URL + "/v1/traces",
+		ServiceName: "app",
+		Header:      http.Header{"Authorization": {"token"}},
+		Interval:    time.Hour,
+	}
+	assert.NoError(t, ex.Flush())
+	now := time.Unix(1, 0)
+	s := &Span{
+		Name:       "GET /",
+		TraceID:    TraceID{1},
+		SpanID:     SpanID{2},
+		Start:      now,
+		End:        now.Add(time.Second),
+		Attributes: Map{"http.response.status_code": 500, "url.path": "/"},
+	}
+	s.RecordError(io.EOF)
+	assert.NoError(t, ex.Export(s))
+	assert.NoError(t, ex.Flush())
+
+	var body struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []Map `json:"attributes"`
+			} `json:"resource"`
+			ScopeSpans []struct {
+				Spans []Map `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	assert.NoError(t, json.Unmarshal(<-bodies, &body))
+	rs := body.ResourceSpans[0]
+	assert.Equal(t, "app", rs.Resource.Attributes[0]["value"].(map[string]interface{})["stringValue"])
+	span := rs.ScopeSpans[0].Spans[0]
+	assert.Equal(t, "01000000000000000000000000000000", span["traceId"])
+	assert.Equal(t, "0200000000000000", span["spanId"])
+	assert.Nil(t, span["parentSpanId"])
+	assert.Equal(t, "1000000000", span["startTimeUnixNano"])
+	assert.Equal(t, "2000000000", span["endTimeUnixNano"])
+	assert.Equal(t, map[string]interface{}{"code": float64(2), "message": "EOF"}, span["status"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"key": "http.response.status_code", "value": map[string]interface{}{"intValue": "500"}},
+		map[string]interface{}{"key": "url.path", "value": map[string]interface{}{"stringValue": "/"}},
+	}, span["attributes"])
+
+	// batch is full
+	ex.BatchSize = 1
+	assert.NoError(t, ex.Export(s))
+	select {
+	case <-bodies:
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch not sent")
+	}
+}
+
+func TestOTLPExporterBackpressure(t *testing.T) {
+	assert.NotZero(t, otlpClient.Timeout)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	requests := 0
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		<-release
+	}))
+	defer collector.Close()
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+	waitCount := func(n int) {
+		for deadline := time.Now().Add(5 * time.Second); count() != n; {
+			if time.Now().After(deadline) {
+				t.Fatalf("%d requests, expected %d", count(), n)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	ex := &OTLPExporter{
+		Endpoint:     collector.URL,
+		BatchSize:    1,
+		MaxQueueSize: 2,
+		Interval:     time.Hour,
+	}
+	s := &Span{Name: "GET /", TraceID: TraceID{1}, SpanID: SpanID{2}}
+	assert.NoError(t, ex.Export(s))
+	waitCount(1)
+	// the collector hangs, only one flush is in flight and the queue is full
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, ex.Export(s))
+	}
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 1, count())
+	assert.Equal(t, uint64(3), ex.Dropped())
+
+	close(release)
+	waitCount(3)
+}
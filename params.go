@@ -0,0 +1,86 @@
+package ctx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// ParamErrorHandler converts the conversion error of the router param k with
+// value v into the error returned by the typed accessors, e.g. c.ParamInt.
+// By default it's a 400 HTTPError, so the handler can return it directly.
+var ParamErrorHandler = func(c *Context, k, v string, err error) error {
+	return NewHTTPError(http.StatusBadRequest,
+		fmt.Sprintf("invalid param %s: %q", k, v))
+}
+
+// ParamsAll returns all the router params in the order of the route path.
+func (c *Context) ParamsAll() httprouter.Params {
+	ps := c.routerParams()
+	return append(make(httprouter.Params, 0, len(ps)), ps...)
+}
+
+// ParamInt returns the router param k as an int.
+func (c *Context) ParamInt(k string) (int, error) {
+	v := c.Params(k)
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, ParamErrorHandler(c, k, v, err)
+	}
+	return i, nil
+}
+
+// ParamInt64 returns the router param k as an int64.
+func (c *Context) ParamInt64(k string) (int64, error) {
+	v := c.Params(k)
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, ParamErrorHandler(c, k, v, err)
+	}
+	return i, nil
+}
+
+// errInvalidUUID is the error of an invalid UUID.
+var errInvalidUUID = errors.New("invalid uuid")
+
+// ParamUUID returns the router param k as a UUID in the canonical lowercase
+// form, e.g. "123e4567-e89b-12d3-a456-426614174000".
+func (c *Context) ParamUUID(k string) (string, error) {
+	v := c.Params(k)
+	uuid, ok := parseUUID(v)
+	if !ok {
+		return "", ParamErrorHandler(c, k, v, errInvalidUUID)
+	}
+	return uuid, nil
+}
+
+// parseUUID returns the canonical form of the UUID s, the braces and the
+// "urn:uuid:" prefix are accepted.
+func parseUUID(s string) (string, bool) {
+	if len(s) == 38 && s[0] == '{' && s[37] == '}' {
+		s = s[1:37]
+	} else if len(s) == 45 && strings.EqualFold(s[:9], "urn:uuid:") {
+		s = s[9:]
+	}
+	if len(s) != 36 {
+		return "", false
+	}
+	b := []byte(strings.ToLower(s))
+	for i, ch := range b {
+		switch i {
+		case 8, 13, 18, 23:
+			if ch != '-' {
+				return "", false
+			}
+		default:
+			if !(ch >= '0' && ch <= '9') && !(ch >= 'a' && ch <= 'f') {
+				return "", false
+			}
+		}
+	}
+	return string(b), true
+}
@@ -0,0 +1,69 @@
+package ctx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParams(t *testing.T) {
+	resetRouter()
+	GET("/users/:id/posts/:post/*rest", func(c *Context) error {
+		id, err := c.ParamInt("id")
+		if err != nil {
+			return err
+		}
+		post, err := c.ParamUUID("post")
+		if err != nil {
+			return err
+		}
+		id64, err := c.ParamInt64("id")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(id), id64)
+		assert.Equal(t, httprouter.Params{
+			{Key: "id", Value: c.Params("id")},
+			{Key: "post", Value: c.Params("post")},
+			{Key: "rest", Value: c.Params("rest")},
+		}, c.ParamsAll())
+		return c.String(post)
+	})
+
+	res := httptest.NewRecorder()
+	routerIns.r.ServeHTTP(res, httptest.NewRequest(http.MethodGet,
+		"/users/1/posts/123E4567-E89B-12D3-A456-426614174000/a/b", nil))
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "123e4567-e89b-12d3-a456-426614174000", res.Body.String())
+
+	res = httptest.NewRecorder()
+	routerIns.r.ServeHTTP(res, httptest.NewRequest(http.MethodGet,
+		"/users/x/posts/123e4567-e89b-12d3-a456-426614174000/", nil))
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = httptest.NewRecorder()
+	routerIns.r.ServeHTTP(res, httptest.NewRequest(http.MethodGet,
+		"/users/1/posts/123e4567/", nil))
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+}
+
+func TestParseUUID(t *testing.T) {
+	for _, s := range []string{
+		"123e4567-e89b-12d3-a456-426614174000",
+		"{123e4567-e89b-12d3-a456-426614174000}",
+		"urn:uuid:123e4567-e89b-12d3-a456-426614174000",
+	} {
+		uuid, ok := parseUUID(s)
+		assert.True(t, ok, s)
+		assert.Equal(t, "123e4567-e89b-12d3-a456-426614174000", uuid)
+	}
+	for _, s := range []string{
+		"", "123e4567e89b12d3a456426614174000",
+		"123e4567-e89b-12d3-a456-42661417400g",
+		"123e4567+e89b-12d3-a456-426614174000",
+	} {
+		_, ok := parseUUID(s)
+		assert.False(t, ok, s)
+	}
+}
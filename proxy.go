@@ -0,0 +1,189 @@
+package ctx
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// trustedProxies is the networks of the trusted proxies.
+var trustedProxies []netip.Prefix
+
+// SetTrustedProxies sets the CIDRs or IPs of the trusted proxies. The
+// forwarding headers are only honored when the peer of the request is a
+// trusted proxy. Call it with nothing to trust no proxy.
+func SetTrustedProxies(cidrs ...string) error {
+	prefixes, err := parsePrefixes(cidrs)
+	if err != nil {
+		return e("set trusted proxies error", err)
+	}
+	trustedProxies = prefixes
+	return nil
+}
+
+// parsePrefixes parses the CIDRs or IPs.
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, s := range cidrs {
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
+
+// prefixesContain returns if addr is in any of prefixes.
+func prefixesContain(prefixes []netip.Prefix, addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTrustedProxy returns if addr is a trusted proxy.
+func isTrustedProxy(addr netip.Addr) bool {
+	return addr.IsValid() && prefixesContain(trustedProxies, addr)
+}
+
+// forwarded is a hop of the forwarding headers.
+type forwarded struct {
+	addr  netip.Addr
+	proto string
+	host  string
+}
+
+// parseNode parses the IP of a node in the forwarding headers, the port and
+// the brackets of IPv6 are removed.
+func parseNode(s string) netip.Addr {
+	s = strings.Trim(strings.TrimSpace(s), `"`)
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, _ := netip.ParseAddr(strings.Trim(s, "[]"))
+	return addr.Unmap()
+}
+
+// forwardedHops returns the hops of the request from the client to the
+// nearest proxy, from Forwarded, X-Forwarded-For or X-Real-IP.
+func (c *Context) forwardedHops() []forwarded {
+	header := c.ReqHeader()
+	hops := []forwarded{}
+	if values := header.Values("Forwarded"); len(values) != 0 {
+		for _, element := range strings.Split(strings.Join(values, ","), ",") {
+			hop := forwarded{}
+			for _, pair := range strings.Split(element, ";") {
+				k, v, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				v = strings.Trim(v, `"`)
+				switch strings.ToLower(k) {
+				case "for":
+					hop.addr = parseNode(v)
+				case "proto":
+					hop.proto = strings.ToLower(v)
+				case "host":
+					hop.host = v
+				}
+			}
+			hops = append(hops, hop)
+		}
+		return hops
+	}
+	if values := header.Values("X-Forwarded-For"); len(values) != 0 {
+		for _, v := range strings.Split(strings.Join(values, ","), ",") {
+			hops = append(hops, forwarded{addr: parseNode(v)})
+		}
+		// the nearest proxy sets X-Forwarded-Proto and X-Forwarded-Host
+		last := &hops[len(hops)-1]
+		last.proto = strings.ToLower(lastValue(header.Get("X-Forwarded-Proto")))
+		last.host = lastValue(header.Get("X-Forwarded-Host"))
+		return hops
+	}
+	if v := header.Get("X-Real-IP"); v != "" {
+		hops = append(hops, forwarded{addr: parseNode(v)})
+	}
+	return hops
+}
+
+// lastValue returns the last value of the comma separated list s.
+func lastValue(s string) string {
+	if i := strings.LastIndexByte(s, ','); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSpace(s)
+}
+
+// clientHop returns the hop of the client. It walks the hops from the nearest
+// proxy and skips the trusted proxies. ok is false if the peer is not a
+// trusted proxy or there is no forwarding header.
+func (c *Context) clientHop() (hop forwarded, ok bool) {
+	if !isTrustedProxy(parseNode(c.Req.RemoteAddr)) {
+		return hop, false
+	}
+	hops := c.forwardedHops()
+	if len(hops) == 0 {
+		return hop, false
+	}
+	client := len(hops) - 1
+	for client > 0 && isTrustedProxy(hops[client].addr) {
+		client--
+	}
+	hop = hops[client]
+	// fall back to the proto and the host of the nearest hop, the hops
+	// before the client are set by the client, so they are ignored
+	for i := len(hops) - 1; i > client; i-- {
+		if hop.proto == "" {
+			hop.proto = hops[i].proto
+		}
+		if hop.host == "" {
+			hop.host = hops[i].host
+		}
+	}
+	return hop, true
+}
+
+// ClientIP returns the IP of the client. The forwarding headers Forwarded,
+// X-Forwarded-For and X-Real-IP are honored only if the peer is a trusted
+// proxy, see SetTrustedProxies.
+func (c *Context) ClientIP() string {
+	if hop, ok := c.clientHop(); ok && hop.addr.IsValid() {
+		return hop.addr.String()
+	}
+	if addr := parseNode(c.Req.RemoteAddr); addr.IsValid() {
+		return addr.String()
+	}
+	return c.Req.RemoteAddr
+}
+
+// Scheme returns the scheme "http" or "https" the client used. Forwarded and
+// X-Forwarded-Proto are honored only if the peer is a trusted proxy.
+func (c *Context) Scheme() string {
+	if hop, ok := c.clientHop(); ok &&
+		(hop.proto == "http" || hop.proto == "https") {
+		return hop.proto
+	}
+	if c.Req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// RealHost returns the host the client requested. Forwarded and
+// X-Forwarded-Host are honored only if the peer is a trusted proxy.
+func (c *Context) RealHost() string {
+	if hop, ok := c.clientHop(); ok && hop.host != "" {
+		return hop.host
+	}
+	return c.Host()
+}
@@ -0,0 +1,75 @@
+package ctx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func proxyContext(remote string, header map[string]string) *Context {
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	req.RemoteAddr = remote
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	return getContext(httptest.NewRecorder(), req)
+}
+
+func TestClientIP(t *testing.T) {
+	assert.Error(t, SetTrustedProxies("10.0.0.0/33"))
+	assert.NoError(t, SetTrustedProxies("10.0.0.0/8", "fd00::1"))
+	defer SetTrustedProxies()
+
+	xff := map[string]string{
+		"X-Forwarded-For":   "1.1.1.1, 2.2.2.2, 10.0.0.2",
+		"X-Forwarded-Proto": "https",
+		"X-Forwarded-Host":  "api.example.com",
+	}
+	// untrusted peer
+	c := proxyContext("3.3.3.3:1234", xff)
+	assert.Equal(t, "3.3.3.3", c.ClientIP())
+	assert.Equal(t, "http", c.Scheme())
+	assert.Equal(t, "example.com", c.RealHost())
+
+	c = proxyContext("10.0.0.1:1234", xff)
+	assert.Equal(t, "2.2.2.2", c.ClientIP())
+	assert.Equal(t, "https", c.Scheme())
+	assert.Equal(t, "api.example.com", c.RealHost())
+
+	c = proxyContext("[fd00::1]:1234", map[string]string{
+		"Forwarded": `for=1.1.1.1;proto=http, for="[2001:db8::1]:4711";proto=https;host=a.com`,
+	})
+	assert.Equal(t, "2001:db8::1", c.ClientIP())
+	assert.Equal(t, "https", c.Scheme())
+	assert.Equal(t, "a.com", c.RealHost())
+
+	// the hops before the client are spoofable
+	c = proxyContext("10.0.0.1:1234", map[string]string{
+		"Forwarded": "for=9.9.9.9;host=evil.com;proto=https, for=5.5.5.5",
+	})
+	assert.Equal(t, "5.5.5.5", c.ClientIP())
+	assert.Equal(t, "http", c.Scheme())
+	assert.Equal(t, "example.com", c.RealHost())
+	c = proxyContext("10.0.0.1:1234", map[string]string{
+		"Forwarded": "for=9.9.9.9;host=evil.com, for=5.5.5.5, for=10.0.0.2;host=a.com;proto=https",
+	})
+	assert.Equal(t, "5.5.5.5", c.ClientIP())
+	assert.Equal(t, "https", c.Scheme())
+	assert.Equal(t, "a.com", c.RealHost())
+
+	c = proxyContext("10.0.0.1:1234", map[string]string{"X-Real-IP": "4.4.4.4"})
+	assert.Equal(t, "4.4.4.4", c.ClientIP())
+
+	// all hops are trusted
+	c = proxyContext("10.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.0.0.3"})
+	assert.Equal(t, "10.0.0.3", c.ClientIP())
+
+	c = proxyContext("10.0.0.1:1234", map[string]string{"Forwarded": "for=unknown"})
+	assert.Equal(t, "10.0.0.1", c.ClientIP())
+
+	c = proxyContext("10.0.0.1:1234", nil)
+	assert.Equal(t, "10.0.0.1", c.ClientIP())
+	assert.Equal(t, "http", c.Scheme())
+}
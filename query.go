@@ -0,0 +1,104 @@
+package ctx
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QueryDefault returns the value of k in the URL query, def if k not exists.
+// An empty value is returned as is.
+func (c *Context) QueryDefault(k, def string) string {
+	if vs := c.queryValues()[k]; len(vs) != 0 {
+		return vs[0]
+	}
+	return def
+}
+
+// QueryArray returns all the values of k in the URL query, both k=a&k=b and
+// k[]=a&k[]=b.
+func (c *Context) QueryArray(k string) []string {
+	q := c.queryValues()
+	values := append([]string(nil), q[k]...)
+	return append(values, q[k+"[]"]...)
+}
+
+// QueryMap returns the values of k in the URL query with the map syntax,
+// e.g. {"a": "b"} of filter[a]=b with k "filter".
+func (c *Context) QueryMap(k string) map[string]string {
+	m := make(map[string]string)
+	for key, vs := range c.queryValues() {
+		if len(vs) == 0 || !strings.HasPrefix(key, k+"[") ||
+			!strings.HasSuffix(key, "]") || len(key) <= len(k)+2 {
+			continue
+		}
+		m[key[len(k)+1:len(key)-1]] = vs[0]
+	}
+	return m
+}
+
+// QueryFloat64 returns a float64 value and error if ParseFloat wrong.
+func (c *Context) QueryFloat64(k string) (float64, error) {
+	return strconv.ParseFloat(c.Query(k), 64)
+}
+
+// QueryDuration returns a time.Duration value, e.g. "1m30s", and error if
+// ParseDuration wrong.
+func (c *Context) QueryDuration(k string) (time.Duration, error) {
+	return time.ParseDuration(c.Query(k))
+}
+
+// QueryTime returns a time.Time value in layout and error if Parse wrong.
+func (c *Context) QueryTime(k, layout string) (time.Time, error) {
+	return time.Parse(layout, c.Query(k))
+}
+
+// QueryIntOr returns a int value, def if k not exists or invalid.
+func (c *Context) QueryIntOr(k string, def int) int {
+	if v, err := c.QueryInt(k); err == nil {
+		return v
+	}
+	return def
+}
+
+// QueryInt64Or returns a int64 value, def if k not exists or invalid.
+func (c *Context) QueryInt64Or(k string, def int64) int64 {
+	if v, err := c.QueryInt64(k); err == nil {
+		return v
+	}
+	return def
+}
+
+// QueryBoolOr returns a bool value, def if k not exists or invalid.
+func (c *Context) QueryBoolOr(k string, def bool) bool {
+	if v, err := c.QueryBool(k); err == nil {
+		return v
+	}
+	return def
+}
+
+// QueryFloat64Or returns a float64 value, def if k not exists or invalid.
+func (c *Context) QueryFloat64Or(k string, def float64) float64 {
+	if v, err := c.QueryFloat64(k); err == nil {
+		return v
+	}
+	return def
+}
+
+// QueryDurationOr returns a time.Duration value, def if k not exists or
+// invalid.
+func (c *Context) QueryDurationOr(k string, def time.Duration) time.Duration {
+	if v, err := c.QueryDuration(k); err == nil {
+		return v
+	}
+	return def
+}
+
+// QueryTimeOr returns a time.Time value in layout, def if k not exists or
+// invalid.
+func (c *Context) QueryTimeOr(k, layout string, def time.Time) time.Time {
+	if v, err := c.QueryTime(k, layout); err == nil {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,86 @@
+package ctx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryAndForm(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/?a=query&q=1",
+		strings.NewReader(url.Values{"a": {"form"}, "f": {"2"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	c := getContext(httptest.NewRecorder(), req)
+	assert.Equal(t, "query", c.Query("a"))
+	assert.Equal(t, "form", c.Form("a"))
+	assert.Equal(t, "", c.Query("f"))
+	assert.Equal(t, "", c.Form("q"))
+	assert.True(t, c.Exists("q"))
+	assert.True(t, c.Exists("f"))
+	assert.False(t, c.Exists("x"))
+
+	c = getContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/?a=1", nil))
+	assert.Equal(t, "1", c.Query("a"))
+	assert.Equal(t, "", c.Form("a"))
+
+	// multipart
+	c = getContext(httptest.NewRecorder(), multipartRequest(map[string][]byte{
+		"b.txt": []byte("hello"),
+	}))
+	assert.Equal(t, "value", c.Form("name"))
+	assert.Equal(t, "", c.Query("name"))
+	files, err := c.Files("files", nil)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	// the form is parsed by c.Files before
+	c = getContext(httptest.NewRecorder(), multipartRequest(map[string][]byte{
+		"b.txt": []byte("hello"),
+	}))
+	_, err = c.Files("files", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "value", c.Form("name"))
+}
+
+func TestQueryAccessors(t *testing.T) {
+	c := getContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet,
+		"/?empty=&ids=1&ids=2&ids[]=3&filter[name]=bob&filter[age]=3&filter=x&filter[]=y"+
+			"&f=1.5&d=1m30s&t=2024-01-02&i=7&b=true&bad=x", nil))
+	assert.Equal(t, "", c.QueryDefault("empty", "def"))
+	assert.Equal(t, "def", c.QueryDefault("none", "def"))
+	assert.Equal(t, []string{"1", "2", "3"}, c.QueryArray("ids"))
+	assert.Empty(t, c.QueryArray("none"))
+	assert.Equal(t, map[string]string{"name": "bob", "age": "3"}, c.QueryMap("filter"))
+	assert.Empty(t, c.QueryMap("none"))
+
+	f, err := c.QueryFloat64("f")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, f)
+	d, err := c.QueryDuration("d")
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Second, d)
+	tm, err := c.QueryTime("t", "2006-01-02")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), tm)
+	_, err = c.QueryDuration("bad")
+	assert.Error(t, err)
+
+	def := time.Unix(0, 0)
+	assert.Equal(t, 7, c.QueryIntOr("i", 1))
+	assert.Equal(t, 1, c.QueryIntOr("bad", 1))
+	assert.Equal(t, int64(7), c.QueryInt64Or("i", 1))
+	assert.Equal(t, int64(1), c.QueryInt64Or("none", 1))
+	assert.True(t, c.QueryBoolOr("b", false))
+	assert.True(t, c.QueryBoolOr("bad", true))
+	assert.Equal(t, 1.5, c.QueryFloat64Or("f", 0))
+	assert.Equal(t, 2.5, c.QueryFloat64Or("bad", 2.5))
+	assert.Equal(t, 90*time.Second, c.QueryDurationOr("d", 0))
+	assert.Equal(t, time.Second, c.QueryDurationOr("none", time.Second))
+	assert.Equal(t, tm, c.QueryTimeOr("t", "2006-01-02", def))
+	assert.Equal(t, def, c.QueryTimeOr("bad", "2006-01-02", def))
+}
@@ -0,0 +1,160 @@
+package ctx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Renderer renders the template `name` with data into w.
+type Renderer interface {
+	Render(w io.Writer, name string, data interface{}) error
+}
+
+// DefaultRenderer is the Renderer used by c.Render.
+// NOTE: set it before use *Context.Render, e.g. to a *HTMLRenderer.
+var DefaultRenderer Renderer
+
+// HTMLRenderer is the html/template Renderer. Every page is parsed with the
+// layout and the partials, so a page can fill the blocks of the layout with
+// {{define "content"}}...{{end}}. Templates are named by their path in FS,
+// e.g. {{template "partials/nav.html" .}}.
+//
+// The template functions `url` (see URL) and `asset` (prepends AssetPrefix to
+// a path) are always available.
+type HTMLRenderer struct {
+	// FS is the templates, use os.DirFS to load them from disk.
+	FS fs.FS
+	// Ext is the extension of the templates, default is ".html".
+	Ext string
+	// Layout is the template executed for every page, empty means the page
+	// is executed by itself.
+	Layout string
+	// Partials is the directory of the templates shared by all pages.
+	Partials string
+	// AssetPrefix is the prefix added by the `asset` function, e.g.
+	// "/static".
+	AssetPrefix string
+	// Funcs is the extra template functions.
+	Funcs template.FuncMap
+	// Reload re-parses the templates on every render, for development.
+	Reload bool
+
+	mu    sync.Mutex
+	pages map[string]*template.Template
+}
+
+// Load parses all the templates. It's called by the first Render, call it
+// to find the template errors at startup.
+func (r *HTMLRenderer) Load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.load()
+}
+
+// Render implements the Renderer interface.
+func (r *HTMLRenderer) Render(w io.Writer, name string, data interface{}) error {
+	r.mu.Lock()
+	if r.pages == nil || r.Reload {
+		if err := r.load(); err != nil {
+			r.mu.Unlock()
+			return err
+		}
+	}
+	t, ok := r.pages[name]
+	r.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("template %q not found", name)
+	}
+	if r.Layout != "" {
+		return t.ExecuteTemplate(w, r.Layout, data)
+	}
+	return t.Execute(w, data)
+}
+
+// load parses all the templates in r.FS.
+func (r *HTMLRenderer) load() error {
+	if r.FS == nil {
+		return errors.New("nil template FS")
+	}
+	ext := r.Ext
+	if ext == "" {
+		ext = ".html"
+	}
+	partials := strings.Trim(r.Partials, "/")
+	shared := map[string]string{}
+	pages := map[string]string{}
+	err := fs.WalkDir(r.FS, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(p) != ext {
+			return err
+		}
+		b, err := fs.ReadFile(r.FS, p)
+		if err != nil {
+			return err
+		}
+		if p == r.Layout ||
+			(partials != "" && strings.HasPrefix(p, partials+"/")) {
+			shared[p] = string(b)
+		} else {
+			pages[p] = string(b)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if r.Layout != "" {
+		if _, ok := shared[r.Layout]; !ok {
+			return fmt.Errorf("layout %q not found", r.Layout)
+		}
+	}
+	funcs := template.FuncMap{
+		"url": URL,
+		"asset": func(p string) string {
+			return strings.TrimSuffix(r.AssetPrefix, "/") +
+				"/" + strings.TrimPrefix(p, "/")
+		},
+	}
+	for k, f := range r.Funcs {
+		funcs[k] = f
+	}
+	parsed := make(map[string]*template.Template, len(pages))
+	for name, text := range pages {
+		t := template.New(name).Funcs(funcs)
+		for n, s := range shared {
+			if _, err := t.New(n).Parse(s); err != nil {
+				return err
+			}
+		}
+		// parse the page last, so its blocks override the layout's.
+		if _, err := t.Parse(text); err != nil {
+			return err
+		}
+		parsed[name] = t
+	}
+	r.pages = parsed
+	return nil
+}
+
+// Render response the current request with the template `name` rendered by
+// DefaultRenderer. The output is buffered, so a template error is returned
+// without writing a half page.
+func (c *Context) Render(name string, data interface{}) error {
+	if DefaultRenderer == nil {
+		return e("render error", errors.New("nil DefaultRenderer"))
+	}
+	buf := new(bytes.Buffer)
+	if err := DefaultRenderer.Render(buf, name, data); err != nil {
+		return e("render error", err)
+	}
+	if ct := c.Res.Header().Get("Content-Type"); ct == "" {
+		c.Res.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	return c.Write(buf.Bytes())
+}
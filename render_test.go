@@ -0,0 +1,89 @@
+package ctx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTMLRenderer(t *testing.T) {
+	resetRouter()
+	GET("/users/:id", h).Named("user")
+	fsys := fstest.MapFS{
+		"layout.html": {Data: []byte(
+			`<title>{{block "title" .}}default{{end}}</title>` +
+				`{{template "partials/nav.html" .}}{{template "content" .}}`,
+		)},
+		"partials/nav.html": {Data: []byte(`<nav>{{asset "app.css"}}</nav>`)},
+		"users/show.html": {Data: []byte(
+			`{{define "title"}}{{.Name}}{{end}}` +
+				`{{define "content"}}<a href="{{url "user" "id" .ID}}">{{.Name}}</a>{{end}}`,
+		)},
+		"bad.html": {Data: []byte(`{{define "content"}}{{.Missing.Field}}{{end}}`)},
+	}
+	DefaultRenderer = &HTMLRenderer{
+		FS:          fsys,
+		Layout:      "layout.html",
+		Partials:    "partials",
+		AssetPrefix: "/static/",
+	}
+	defer func() {
+		DefaultRenderer = nil
+	}()
+	assert.NoError(t, DefaultRenderer.(*HTMLRenderer).Load())
+
+	GET("/ok", func(c *Context) error {
+		return c.Render("users/show.html", Map{"ID": 1, "Name": "<b>"})
+	})
+	GET("/bad", func(c *Context) error {
+		return c.Render("bad.html", 1)
+	})
+	GET("/none", func(c *Context) error {
+		return c.Render("none.html", nil)
+	})
+
+	res := serve(http.MethodGet, "/ok")
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "text/html; charset=utf-8", res.Header().Get("Content-Type"))
+	assert.Equal(
+		t,
+		`<title>&lt;b&gt;</title><nav>/static/app.css</nav>`+
+			`<a href="/users/1">&lt;b&gt;</a>`,
+		res.Body.String(),
+	)
+
+	res = serve(http.MethodGet, "/bad")
+	assert.Equal(t, 500, res.Code)
+	assert.NotContains(t, res.Body.String(), "<title>")
+
+	res = serve(http.MethodGet, "/none")
+	assert.Equal(t, 500, res.Code)
+}
+
+func TestHTMLRendererReload(t *testing.T) {
+	fsys := fstest.MapFS{"a.html": {Data: []byte("1")}}
+	r := &HTMLRenderer{FS: fsys, Reload: true}
+	c := getContext(nil, nil)
+	DefaultRenderer = errRenderer{}
+	defer func() {
+		DefaultRenderer = nil
+	}()
+	assert.Error(t, c.Render("a.html", nil))
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, r.Render(buf, "a.html", nil))
+	fsys["a.html"] = &fstest.MapFile{Data: []byte("2")}
+	assert.NoError(t, r.Render(buf, "a.html", nil))
+	assert.Equal(t, "12", buf.String())
+}
+
+type errRenderer struct{}
+
+func (errRenderer) Render(w io.Writer, name string, data interface{}) error {
+	return errors.New("render error")
+}
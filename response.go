@@ -0,0 +1,199 @@
+package ctx
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// response wraps the http.ResponseWriter of a Context, it runs the hooks
+// registered by c.BeforeWrite right before the header is written, and
+// records the status and the size of the response. In the buffered mode, the
+// response is kept in buf until the request is handled, so the middlewares
+// can inspect and rewrite it in the send hooks.
+type response struct {
+	http.ResponseWriter
+	wroteHeader bool
+	status      int
+	size        int64
+	before      []func()
+	after       []func()
+
+	buf  *bytes.Buffer
+	send []func()
+}
+
+// reset resets the response with w.
+func (r *response) reset(w http.ResponseWriter) {
+	r.ResponseWriter = w
+	r.wroteHeader = false
+	r.status = 0
+	r.size = 0
+	r.before = nil
+	r.after = nil
+	r.buf = nil
+	r.send = nil
+}
+
+// runBefore runs the before hooks once.
+func (r *response) runBefore() {
+	hooks := r.before
+	r.before = nil
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
+
+// runAfter runs the after hooks once.
+func (r *response) runAfter() {
+	hooks := r.after
+	r.after = nil
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
+
+// runSend runs the send hooks once and sends the buffered response.
+func (r *response) runSend() {
+	if r.buf == nil {
+		return
+	}
+	hooks := r.send
+	r.send = nil
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+	r.flushBuffer()
+}
+
+// flushBuffer leaves the buffered mode and writes the buffered response.
+func (r *response) flushBuffer() {
+	buf := r.buf
+	r.buf, r.send = nil, nil
+	if buf == nil || !r.wroteHeader {
+		return
+	}
+	h := r.Header()
+	if buf.Len() != 0 && h.Get("Content-Length") == "" {
+		h.Set("Content-Length", strconv.Itoa(buf.Len()))
+	}
+	r.ResponseWriter.WriteHeader(r.status)
+	n, _ := r.ResponseWriter.Write(buf.Bytes())
+	r.size += int64(n)
+}
+
+// WriteHeader implements the http.ResponseWriter interface.
+func (r *response) WriteHeader(code int) {
+	if r.buf != nil && code < 200 {
+		r.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if !r.wroteHeader {
+		r.wroteHeader = true
+		r.runBefore()
+	}
+	if r.status < 200 {
+		r.status = code
+	}
+	if r.buf == nil {
+		r.ResponseWriter.WriteHeader(code)
+	}
+}
+
+// Write implements the http.ResponseWriter interface.
+func (r *response) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	if r.buf != nil {
+		return r.buf.Write(b)
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.size += int64(n)
+	return n, err
+}
+
+// ReadFrom implements the io.ReaderFrom interface, so the underlying writer
+// can still use sendfile.
+func (r *response) ReadFrom(src io.Reader) (int64, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	if r.buf != nil {
+		return r.buf.ReadFrom(src)
+	}
+	n, err := io.Copy(r.ResponseWriter, src)
+	r.size += n
+	return n, err
+}
+
+// Flush implements the http.Flusher interface. A buffered response is sent
+// as is and the send hooks are dropped, since it's streaming.
+func (r *response) Flush() {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.flushBuffer()
+	http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Hijack implements the http.Hijacker interface.
+func (r *response) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.buf, r.send = nil, nil
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil && r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying http.ResponseWriter for
+// http.ResponseController.
+func (r *response) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// BeforeWrite registers f which runs right before the response header is
+// written, or when the handler chain returns without writing. The hooks run
+// in the reverse order of registration.
+func (c *Context) BeforeWrite(f func()) {
+	c.res.before = append(c.res.before, f)
+}
+
+// AfterWrite registers f which runs after the request is handled, including
+// ErrorHandler and PanicHandler. The hooks run in the reverse order of
+// registration.
+func (c *Context) AfterWrite(f func()) {
+	c.res.after = append(c.res.after, f)
+}
+
+// bufferResponse makes the response buffered until the request is handled,
+// then f runs before it's sent, and it can rewrite c.res.status, c.res.buf
+// and the header. The hooks run in the reverse order of registration, and
+// they are dropped if the response is flushed or hijacked.
+func (c *Context) bufferResponse(f func()) {
+	if c.res.buf == nil {
+		if c.res.wroteHeader {
+			return
+		}
+		c.res.buf = new(bytes.Buffer)
+	}
+	c.res.send = append(c.res.send, f)
+}
+
+// ResStatus returns the status code written to the response, 200 if nothing
+// is written.
+func (c *Context) ResStatus() int {
+	if c.res.status == 0 {
+		return http.StatusOK
+	}
+	return c.res.status
+}
+
+// ResSize returns the size of the response body written.
+func (c *Context) ResSize() int64 {
+	return c.res.size
+}
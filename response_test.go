@@ -0,0 +1,44 @@
+package ctx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBeforeWrite(t *testing.T) {
+	res := httptest.NewRecorder()
+	c := getContext(res, httptest.NewRequest(http.MethodGet, "/", nil))
+	order := []int{}
+	c.BeforeWrite(func() {
+		order = append(order, 1)
+	})
+	c.BeforeWrite(func() {
+		order = append(order, 2)
+		c.ResHeader().Set("X-Before", "1")
+	})
+	assert.NoError(t, c.String("ok"))
+	assert.NoError(t, c.Flush())
+	assert.Equal(t, []int{2, 1}, order)
+	assert.Equal(t, "1", res.Header().Get("X-Before"))
+	assert.True(t, res.Flushed)
+	assert.Implements(t, (*http.Hijacker)(nil), c.Res)
+}
+
+func TestAfterWrite(t *testing.T) {
+	res := httptest.NewRecorder()
+	var status int
+	var size int64
+	Handler(func(c *Context) error {
+		c.AfterWrite(func() {
+			status, size = c.ResStatus(), c.ResSize()
+		})
+		assert.Equal(t, 200, c.ResStatus())
+		c.SetStatusCode(http.StatusCreated)
+		return c.String("created")
+	}).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, int64(len("created")), size)
+}
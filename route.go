@@ -0,0 +1,181 @@
+package ctx
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Route is a registered route.
+type Route struct {
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Name    string `json:"name,omitempty"`
+	Group   string `json:"group,omitempty"`
+	Handler string `json:"handler"`
+	// Middleware is the prev handlers and the route's own middleware, in
+	// the order of execution.
+	Middleware []string `json:"middleware,omitempty"`
+	// Next is the next handlers of the route.
+	Next    []string `json:"next,omitempty"`
+	Summary string   `json:"summary,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	// Requires is the policies required by the route and its group.
+	Requires []string `json:"requires,omitempty"`
+
+	r        *router
+	mhs      Handlers
+	doc      routeDoc
+	skipCSRF bool
+	requires []Policy
+}
+
+// Named names the route, so its url can be built by URL.
+func (rt *Route) Named(name string) *Route {
+	rt.Name = name
+	return rt
+}
+
+// URL builds the url of the route named `name`. params are pairs of key and
+// value, e.g. URL("user", "id", 1). The params which are not in the route path
+// are encoded as the query string.
+func URL(name string, params ...interface{}) (string, error) {
+	var rt *Route
+	for i := len(routerIns.routes) - 1; i >= 0; i-- {
+		if routerIns.routes[i].Name == name {
+			rt = routerIns.routes[i]
+			break
+		}
+	}
+	if rt == nil {
+		return "", e("build url error", fmt.Errorf("route %q not found", name))
+	}
+	return rt.URL(params...)
+}
+
+// URLFor is same as URL, it builds the url of the route named `name`.
+func (c *Context) URLFor(name string, params ...interface{}) (string, error) {
+	return URL(name, params...)
+}
+
+// URL builds the url of rt with params, see URL.
+func (rt *Route) URL(params ...interface{}) (string, error) {
+	if len(params)%2 != 0 {
+		return "", e("build url error", errors.New("odd number of params"))
+	}
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		k, ok := params[i].(string)
+		if !ok {
+			return "", e(
+				"build url error",
+				fmt.Errorf("param key %v is not a string", params[i]),
+			)
+		}
+		values[k] = fmt.Sprint(params[i+1])
+	}
+	segments := strings.Split(rt.Path, "/")
+	used := make(map[string]bool)
+	for i, s := range segments {
+		if len(s) == 0 || (s[0] != ':' && s[0] != '*') {
+			continue
+		}
+		k := s[1:]
+		v, ok := values[k]
+		if !ok {
+			return "", e("build url error", fmt.Errorf("missing param %q", k))
+		}
+		used[k] = true
+		if s[0] == ':' {
+			segments[i] = url.PathEscape(v)
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(v, "/"), "/")
+		for j := range parts {
+			parts[j] = url.PathEscape(parts[j])
+		}
+		segments[i] = strings.Join(parts, "/")
+	}
+	u := strings.Join(segments, "/")
+	query := make(url.Values)
+	for k, v := range values {
+		if !used[k] {
+			query.Set(k, v)
+		}
+	}
+	if len(query) != 0 {
+		u += "?" + query.Encode()
+	}
+	return u, nil
+}
+
+// Routes returns all registered routes sorted by path and method.
+func Routes() []Route {
+	routes := make([]Route, 0, len(routerIns.routes))
+	for _, rt := range routerIns.routes {
+		cp := *rt
+		cp.Middleware = append(handlerNames(rt.r.prev), handlerNames(rt.mhs)...)
+		cp.Next = handlerNames(rt.r.next)
+		cp.Requires = append(policyNames(rt.r.requires), policyNames(rt.requires)...)
+		routes = append(routes, cp)
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// DumpRoutes is a Handler which responses all registered routes as a table.
+// It responses json if the query `format` is "json".
+// e.g. ctx.GET("/debug/routes", ctx.DumpRoutes)
+func DumpRoutes(c *Context) error {
+	routes := Routes()
+	if c.Query("format") == "json" {
+		return c.Json(routes)
+	}
+	b := new(strings.Builder)
+	w := tabwriter.NewWriter(b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(
+		w,
+		"METHOD\tPATH\tNAME\tGROUP\tHANDLER\tMIDDLEWARE\tNEXT\tREQUIRES",
+	)
+	for _, rt := range routes {
+		fmt.Fprintf(
+			w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			rt.Method, rt.Path, rt.Name, rt.Group, rt.Handler,
+			strings.Join(rt.Middleware, ","), strings.Join(rt.Next, ","),
+			strings.Join(rt.Requires, " "),
+		)
+	}
+	w.Flush()
+	return c.String(b.String())
+}
+
+// handlerName returns the function name of h.
+func handlerName(h Handler) string {
+	if h == nil {
+		return ""
+	}
+	f := runtime.FuncForPC(reflect.ValueOf(h).Pointer())
+	if f == nil {
+		return "unknown"
+	}
+	return f.Name()
+}
+
+// handlerNames returns the function names of hs.
+func handlerNames(hs Handlers) []string {
+	names := make([]string, 0, len(hs))
+	for _, h := range hs {
+		names = append(names, handlerName(h))
+	}
+	return names
+}
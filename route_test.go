@@ -0,0 +1,71 @@
+package ctx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURL(t *testing.T) {
+	resetRouter()
+	GET("/users/:id", h).Named("user")
+	g := Group("/files")
+	g.GET("/*filepath", h).Named("file")
+
+	u, err := URL("user", "id", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/1", u)
+
+	u, err = URL("user", "id", "a b/c", "page", 2, "q", "x&y")
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/a%20b%2Fc?page=2&q=x%26y", u)
+
+	u, err = getContext(nil, nil).URLFor("file", "filepath", "/a/b c.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "/files/a/b%20c.txt", u)
+
+	_, err = URL("user")
+	assert.Error(t, err)
+	_, err = URL("user", "id")
+	assert.Error(t, err)
+	_, err = URL("none")
+	assert.Error(t, err)
+}
+
+func mw(*Context) error { return nil }
+
+func TestRoutes(t *testing.T) {
+	resetRouter()
+	Use(mw)
+	g := Group("/g")
+	POST("/b", h, mw).Named("b")
+	g.GET("/a", DumpRoutes)
+	GET("/b", h)
+
+	routes := Routes()
+	assert.Len(t, routes, 3)
+	assert.Equal(t, "/b", routes[0].Path)
+	assert.Equal(t, "GET", routes[0].Method)
+	assert.Equal(t, "POST", routes[1].Method)
+	assert.Equal(t, "b", routes[1].Name)
+	assert.Equal(t, []string{
+		"github.com/BouncyElf/ctx.mw", "github.com/BouncyElf/ctx.mw",
+	}, routes[1].Middleware)
+	assert.Equal(t, "/g/a", routes[2].Path)
+	assert.Equal(t, "/g", routes[2].Group)
+	assert.Equal(t, "github.com/BouncyElf/ctx.DumpRoutes", routes[2].Handler)
+
+	req := httptest.NewRequest(http.MethodGet, "/g/a", nil)
+	res := httptest.NewRecorder()
+	routerIns.r.ServeHTTP(res, req)
+	assert.Contains(t, res.Body.String(), "METHOD")
+	assert.Contains(t, res.Body.String(), "/g/a")
+
+	req = httptest.NewRequest(http.MethodGet, "/g/a?format=json", nil)
+	res = httptest.NewRecorder()
+	routerIns.r.ServeHTTP(res, req)
+	assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+	assert.Contains(t, res.Body.String(), `"path":"/g/a"`)
+}
@@ -14,21 +14,31 @@ var routerIns *router
 
 func init() {
 	routerIns = new(router)
-	routerIns.r = httprouter.New()
-	routerIns.r.NotFound = Handler(
-		func(c *Context) error {
-			return ErrNotFound
-		},
-	)
-	routerIns.r.MethodNotAllowed = Handler(
-		func(c *Context) error {
-			return ErrMethodNotAllow
-		},
-	)
-	routerIns.r.HandleOPTIONS = true
-	routerIns.r.HandleMethodNotAllowed = true
-	routerIns.r.RedirectTrailingSlash = true
-	routerIns.r.RedirectFixedPath = true
+	routerIns.r = newHTTPRouter()
+}
+
+// newHTTPRouter returns the httprouter.Router of ctx.
+func newHTTPRouter() *httprouter.Router {
+	r := httprouter.New()
+	r.NotFound = unmatched(ErrNotFound)
+	r.MethodNotAllowed = unmatched(ErrMethodNotAllow)
+	r.HandleOPTIONS = true
+	r.HandleMethodNotAllowed = true
+	r.RedirectTrailingSlash = true
+	r.RedirectFixedPath = true
+	return r
+}
+
+// unmatched returns the handler of the requests matching no route. Only the
+// observers, e.g. the metrics, run before it returns err, the prev handlers
+// are for the routes.
+func unmatched(err error) Handler {
+	return func(c *Context) error {
+		for _, h := range routerIns.unmatched {
+			h(c)
+		}
+		return err
+	}
 }
 
 // Router is the http router in ctx. It's the entry of your app.
@@ -37,6 +47,13 @@ type router struct {
 	prev Handlers
 	next Handlers
 	s    *server
+
+	// group is the prefix of the GroupRouter which owns the router.
+	group    string
+	requires []Policy
+	routes   []*Route
+	// unmatched is the observers of the requests matching no route.
+	unmatched Handlers
 }
 
 // Run runs the app, default port is '8080'.
@@ -57,9 +74,21 @@ func Run(addr ...string) {
 	}
 }
 
+// App returns the http.Handler of the app, for tests and the servers not
+// started by Run.
+func App() http.Handler {
+	return routerIns.r
+}
+
 // Shutdown shutdown the server gracefully, when t <= 0, it wait for all request
 // finished. Othercase it will shutdown right after t.
+// The readiness fails once Shutdown begins, and the server keeps accepting the
+// requests for Config.DrainDelay, so the load balancers can notice it.
 func Shutdown(t time.Duration) {
+	draining.Store(true)
+	if Config.DrainDelay > 0 {
+		time.Sleep(Config.DrainDelay)
+	}
 	if t <= 0 {
 		routerIns.s.s.Shutdown(context.Background())
 		return
@@ -88,54 +117,72 @@ func Next(hs ...Handler) {
 }
 
 // GET register a router with method "GET".
-func GET(path string, h Handler, mhs ...Handler) {
-	routerIns.push("GET", path, h, mhs...)
+func GET(path string, h Handler, mhs ...Handler) *Route {
+	return routerIns.push("GET", path, h, mhs...)
 }
 
 // POST register a router with method "POST".
-func POST(path string, h Handler, mhs ...Handler) {
-	routerIns.push("POST", path, h, mhs...)
+func POST(path string, h Handler, mhs ...Handler) *Route {
+	return routerIns.push("POST", path, h, mhs...)
 }
 
 // HEAD register a router with method "HEAD".
-func HEAD(path string, h Handler, mhs ...Handler) {
-	routerIns.push("HEAD", path, h, mhs...)
+func HEAD(path string, h Handler, mhs ...Handler) *Route {
+	return routerIns.push("HEAD", path, h, mhs...)
 }
 
 // OPTIONS register a router with method "OPTIONS".
-func OPTIONS(path string, h Handler, mhs ...Handler) {
-	routerIns.push("OPTIONS", path, h, mhs...)
+func OPTIONS(path string, h Handler, mhs ...Handler) *Route {
+	return routerIns.push("OPTIONS", path, h, mhs...)
 }
 
 // PUT register a router with method "PUT".
-func PUT(path string, h Handler, mhs ...Handler) {
-	routerIns.push("PUT", path, h, mhs...)
+func PUT(path string, h Handler, mhs ...Handler) *Route {
+	return routerIns.push("PUT", path, h, mhs...)
 }
 
 // PATCH register a router with method "PATCH".
-func PATCH(path string, h Handler, mhs ...Handler) {
-	routerIns.push("PATCH", path, h, mhs...)
+func PATCH(path string, h Handler, mhs ...Handler) *Route {
+	return routerIns.push("PATCH", path, h, mhs...)
 }
 
 // DELETE register a router with method "DELETE".
-func DELETE(path string, h Handler, mhs ...Handler) {
-	routerIns.push("DELETE", path, h, mhs...)
+func DELETE(path string, h Handler, mhs ...Handler) *Route {
+	return routerIns.push("DELETE", path, h, mhs...)
 }
 
-// push register router with httprouter's method `(*httprouter.Router).Handler`.
-func (r *router) push(method, path string, h Handler, mhs ...Handler) {
+// push register router with httprouter's method `(*httprouter.Router).Handler`,
+// and returns the registered route.
+func (r *router) push(method, path string, h Handler, mhs ...Handler) *Route {
+	rt := &Route{
+		Method:  method,
+		Path:    path,
+		Group:   r.group,
+		Handler: handlerName(h),
+		r:       r,
+		mhs:     mhs,
+	}
 	routerIns.r.Handler(method, path, Handler(
 		func(c *Context) error {
+			c.route = rt
 			if err := r.prev.Run(c); err != nil {
 				return err
 			}
 			if err := Handlers(mhs).Run(c); err != nil {
 				return err
 			}
+			if c.abort {
+				return nil
+			}
+			if err := rt.authorize(c); err != nil {
+				return err
+			}
 			if err := Handlers([]Handler{h}).Run(c); err != nil {
 				return err
 			}
 			return r.next.Run(c)
 		},
 	))
+	routerIns.routes = append(routerIns.routes, rt)
+	return rt
 }
@@ -1,9 +1,10 @@
 package ctx
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
-	"github.com/julienschmidt/httprouter"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -30,8 +31,33 @@ func TestRouterMethod(t *testing.T) {
 }
 
 func resetRouter() {
-	routerIns.r = httprouter.New()
+	routerIns.r = newHTTPRouter()
 	routerIns.next = nil
 	routerIns.prev = nil
+	routerIns.routes = nil
+	routerIns.unmatched = nil
 	routerIns.s = newServer(":8080", routerIns.r)
 }
+
+func TestNoDefaultServeMux(t *testing.T) {
+	// importing ctx must not register the debug handlers, see package debug
+	for _, path := range []string{"/debug/pprof/", "/debug/vars"} {
+		_, pattern := http.DefaultServeMux.Handler(httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Empty(t, pattern, path)
+	}
+}
+
+func TestUnmatched(t *testing.T) {
+	resetRouter()
+	// the middleware of the routes doesn't run for the unmatched requests
+	Use(func(c *Context) error {
+		return NewHTTPError(http.StatusUnauthorized)
+	})
+	GET("/", h)
+	res := httptest.NewRecorder()
+	routerIns.r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/nope", nil))
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	res = httptest.NewRecorder()
+	routerIns.r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, res.Code)
+}
@@ -0,0 +1,196 @@
+package ctx
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSPNonce is the source placeholder of the per request nonce in CSP, it's
+// replaced by 'nonce-...' of c.CSPNonce().
+const CSPNonce = "'nonce'"
+
+// CSP is the Content-Security-Policy builder.
+// e.g. NewCSP().Add("default-src", "'self'").Add("script-src", CSPNonce)
+type CSP struct {
+	directives []string
+	sources    map[string][]string
+}
+
+// NewCSP returns an empty CSP.
+func NewCSP() *CSP {
+	return &CSP{sources: make(map[string][]string)}
+}
+
+// Add adds sources to directive.
+func (p *CSP) Add(directive string, sources ...string) *CSP {
+	if _, ok := p.sources[directive]; !ok {
+		p.directives = append(p.directives, directive)
+	}
+	p.sources[directive] = append(p.sources[directive], sources...)
+	return p
+}
+
+// hasNonce returns if the policy has CSPNonce.
+func (p *CSP) hasNonce() bool {
+	for _, sources := range p.sources {
+		for _, s := range sources {
+			if s == CSPNonce {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String returns the policy with CSPNonce replaced by nonce.
+func (p *CSP) String(nonce string) string {
+	parts := make([]string, 0, len(p.directives))
+	for _, d := range p.directives {
+		part := d
+		for _, s := range p.sources[d] {
+			if s == CSPNonce {
+				s = "'nonce-" + nonce + "'"
+			}
+			part += " " + s
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// SecureHeadersConfig is the config of SecureHeaders. The empty fields are
+// not sent.
+type SecureHeadersConfig struct {
+	// HSTSMaxAge is the max-age of Strict-Transport-Security, which is only
+	// sent over HTTPS.
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	// NoSniff sends X-Content-Type-Options: nosniff.
+	NoSniff bool
+	// FrameOptions is X-Frame-Options, e.g. "DENY" or "SAMEORIGIN".
+	FrameOptions      string
+	ReferrerPolicy    string
+	PermissionsPolicy string
+	CSP               *CSP
+	// CSPReportOnly sends CSP as Content-Security-Policy-Report-Only.
+	CSPReportOnly bool
+}
+
+// DefaultSecureHeadersConfig is the config of SecureHeaders(nil).
+var DefaultSecureHeadersConfig = SecureHeadersConfig{
+	HSTSMaxAge:            365 * 24 * time.Hour,
+	HSTSIncludeSubdomains: true,
+	NoSniff:               true,
+	FrameOptions:          "DENY",
+	ReferrerPolicy:        "strict-origin-when-cross-origin",
+	CSP:                   NewCSP().Add("default-src", "'self'"),
+}
+
+// SecureHeaders returns the middleware which sets the security headers.
+// cfg is DefaultSecureHeadersConfig if nil.
+func SecureHeaders(cfg *SecureHeadersConfig) Handler {
+	conf := DefaultSecureHeadersConfig
+	if cfg != nil {
+		conf = *cfg
+	}
+	hsts := ""
+	if conf.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.FormatInt(int64(conf.HSTSMaxAge.Seconds()), 10)
+		if conf.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if conf.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+	cspHeader := "Content-Security-Policy"
+	if conf.CSPReportOnly {
+		cspHeader = "Content-Security-Policy-Report-Only"
+	}
+	nonce := conf.CSP != nil && conf.CSP.hasNonce()
+	return func(c *Context) error {
+		header := c.ResHeader()
+		if hsts != "" && c.Scheme() == "https" {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		if conf.NoSniff {
+			header.Set("X-Content-Type-Options", "nosniff")
+		}
+		if conf.FrameOptions != "" {
+			header.Set("X-Frame-Options", conf.FrameOptions)
+		}
+		if conf.ReferrerPolicy != "" {
+			header.Set("Referrer-Policy", conf.ReferrerPolicy)
+		}
+		if conf.PermissionsPolicy != "" {
+			header.Set("Permissions-Policy", conf.PermissionsPolicy)
+		}
+		if conf.CSP != nil {
+			if nonce {
+				b := make([]byte, 16)
+				rand.Read(b)
+				c.cspNonce = base64.StdEncoding.EncodeToString(b)
+			}
+			header.Set(cspHeader, conf.CSP.String(c.cspNonce))
+		}
+		return nil
+	}
+}
+
+// CSPNonce returns the CSP nonce of the current request for the inline
+// scripts and styles in templates, e.g. <script nonce="{{.Nonce}}">. It's ""
+// if the CSP of SecureHeaders has no CSPNonce.
+func (c *Context) CSPNonce() string {
+	return c.cspNonce
+}
+
+// maxCSPReportSize is the max size of a CSP violation report.
+const maxCSPReportSize = 64 << 10
+
+// CSPReport returns the Handler of the report-uri or report-to endpoint of
+// CSP. f is called with every violation report, it logs the reports if nil.
+// e.g. ctx.POST("/csp-report", ctx.CSPReport(nil))
+func CSPReport(f func(c *Context, report Map)) Handler {
+	if f == nil {
+		f = func(c *Context, report Map) {
+			b, _ := json.Marshal(report)
+			log.Printf("%s csp violation: %s\n", "[ctx]", b)
+		}
+	}
+	return func(c *Context) error {
+		b, err := io.ReadAll(io.LimitReader(c.ReqBody(), maxCSPReportSize))
+		if err != nil {
+			return NewHTTPError(http.StatusBadRequest)
+		}
+		// application/csp-report of report-uri
+		var single struct {
+			Report Map `json:"csp-report"`
+		}
+		// application/reports+json of report-to
+		var list []struct {
+			Type string `json:"type"`
+			Body Map    `json:"body"`
+		}
+		if json.Unmarshal(b, &single) == nil && single.Report != nil {
+			f(c, single.Report)
+		} else if json.Unmarshal(b, &list) == nil {
+			for _, r := range list {
+				if r.Type == "csp-violation" {
+					f(c, r.Body)
+				}
+			}
+		} else {
+			return NewHTTPError(http.StatusBadRequest)
+		}
+		c.SetStatusCode(http.StatusNoContent)
+		return nil
+	}
+}
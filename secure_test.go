@@ -0,0 +1,82 @@
+package ctx
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecureHeaders(t *testing.T) {
+	resetRouter()
+	g := Group("/g")
+	Use(SecureHeaders(nil))
+	GET("/", h)
+	g.Use(SecureHeaders(&SecureHeadersConfig{
+		PermissionsPolicy: "camera=()",
+		CSP: NewCSP().
+			Add("default-src", "'self'").
+			Add("script-src", "'self'", CSPNonce).
+			Add("report-uri", "/csp"),
+		CSPReportOnly: true,
+	}))
+	g.GET("/", func(c *Context) error {
+		return c.String(c.CSPNonce())
+	})
+
+	res := serve(http.MethodGet, "/")
+	assert.Equal(t, "nosniff", res.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", res.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "strict-origin-when-cross-origin", res.Header().Get("Referrer-Policy"))
+	assert.Equal(t, "default-src 'self'", res.Header().Get("Content-Security-Policy"))
+	assert.Empty(t, res.Header().Get("Strict-Transport-Security"))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.TLS = &tls.ConnectionState{}
+	res = httptest.NewRecorder()
+	routerIns.r.ServeHTTP(res, req)
+	assert.Equal(
+		t,
+		"max-age=31536000; includeSubDomains",
+		res.Header().Get("Strict-Transport-Security"),
+	)
+
+	res = serve(http.MethodGet, "/g/")
+	nonce := res.Body.String()
+	assert.NotEmpty(t, nonce)
+	assert.Equal(t, "camera=()", res.Header().Get("Permissions-Policy"))
+	assert.Empty(t, res.Header().Get("X-Frame-Options"))
+	assert.Empty(t, res.Header().Get("Content-Security-Policy"))
+	assert.Equal(
+		t,
+		"default-src 'self'; script-src 'self' 'nonce-"+nonce+"'; report-uri /csp",
+		res.Header().Get("Content-Security-Policy-Report-Only"),
+	)
+	assert.NotEqual(t, nonce, serve(http.MethodGet, "/g/").Body.String())
+}
+
+func TestCSPReport(t *testing.T) {
+	resetRouter()
+	reports := []Map{}
+	POST("/csp", CSPReport(func(c *Context, report Map) {
+		reports = append(reports, report)
+	}))
+	for _, body := range []string{
+		`{"csp-report":{"blocked-uri":"a"}}`,
+		`[{"type":"csp-violation","body":{"blockedURL":"b"}},{"type":"other"}]`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/csp", strings.NewReader(body))
+		res := httptest.NewRecorder()
+		routerIns.r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusNoContent, res.Code)
+	}
+	assert.Equal(t, []Map{{"blocked-uri": "a"}, {"blockedURL": "b"}}, reports)
+
+	req := httptest.NewRequest(http.MethodPost, "/csp", strings.NewReader("x"))
+	res := httptest.NewRecorder()
+	routerIns.r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+}
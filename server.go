@@ -1,6 +1,31 @@
 package ctx
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// ServerConfig is the configuration of the server started by Run.
+type ServerConfig struct {
+	// H2C enables HTTP/2 over cleartext TCP, both with prior knowledge and
+	// through the HTTP/1.1 `Upgrade: h2c` mechanism, with
+	// golang.org/x/net/http2/h2c.
+	H2C bool
+
+	// HTTP2 is the HTTP/2 settings of the server, such as
+	// MaxConcurrentStreams. nil means the defaults of net/http.
+	HTTP2 *http.HTTP2Config
+
+	// DrainDelay is the delay of Shutdown between failing the readiness and
+	// closing the listeners.
+	DrainDelay time.Duration
+}
+
+// Config is the configuration of the server. Change it before Run.
+var Config = &ServerConfig{}
 
 type server struct {
 	s *http.Server
@@ -13,6 +38,11 @@ func newServer(addr string, h http.Handler) *server {
 	s := &http.Server{
 		Addr:    addr,
 		Handler: h,
+		HTTP2:   Config.HTTP2,
+	}
+	if Config.H2C {
+		// the HTTP/2 settings are read from s.HTTP2
+		s.Handler = h2c.NewHandler(h, &http2.Server{})
 	}
 	return &server{
 		s: s,
@@ -1,12 +1,88 @@
 package ctx
 
 import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
 )
 
 func TestNewServer(t *testing.T) {
 	s := newServer("", nil)
 	assert.Equal(t, s.s.Addr, ":8080")
 }
+
+func TestH2C(t *testing.T) {
+	Config.H2C = true
+	Config.HTTP2 = &http.HTTP2Config{MaxConcurrentStreams: 10}
+	defer func() {
+		Config.H2C = false
+		Config.HTTP2 = nil
+	}()
+	s := newServer(":0", Handler(func(c *Context) error {
+		assert.NoError(t, c.Flush())
+		return c.String(c.Req.Proto)
+	}))
+	assert.Equal(t, 10, s.s.HTTP2.MaxConcurrentStreams)
+	ts := httptest.NewUnstartedServer(s.s.Handler)
+	ts.Config = s.s
+	ts.Start()
+	defer ts.Close()
+
+	// prior knowledge
+	tr := &http.Transport{Protocols: new(http.Protocols)}
+	tr.Protocols.SetUnencryptedHTTP2(true)
+	res, err := (&http.Client{Transport: tr}).Get(ts.URL)
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	assert.Equal(t, "HTTP/2.0", string(body))
+
+	// upgrade
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: localhost\r\n"+
+		"Connection: Upgrade, HTTP2-Settings\r\nUpgrade: h2c\r\n"+
+		"HTTP2-Settings: AAMAAABkAAQAoAAAAAIAAAAA\r\n\r\n")
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(line, "HTTP/1.1 101"))
+	for line != "\r\n" {
+		line, _ = r.ReadString('\n')
+	}
+	io.WriteString(conn, http2.ClientPreface+"\x00\x00\x00\x04\x00\x00\x00\x00\x00")
+	// the response of stream 1 follows the server SETTINGS
+	var stream1 []byte
+	maxStreams := -1
+	for stream1 == nil {
+		head := make([]byte, 9)
+		_, err := io.ReadFull(r, head)
+		if !assert.NoError(t, err) {
+			return
+		}
+		payload := make([]byte, int(head[0])<<16|int(head[1])<<8|int(head[2]))
+		io.ReadFull(r, payload)
+		if head[3] == 0x4 {
+			// SETTINGS_MAX_CONCURRENT_STREAMS
+			for i := 0; i+6 <= len(payload); i += 6 {
+				if payload[i] == 0 && payload[i+1] == 0x3 {
+					maxStreams = int(binary.BigEndian.Uint32(payload[i+2:]))
+				}
+			}
+		}
+		if head[8] == 1 {
+			stream1 = head
+		}
+	}
+	assert.Equal(t, byte(0x1), stream1[3])
+	assert.Equal(t, 10, maxStreams)
+}
@@ -0,0 +1,411 @@
+package ctx
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// errSessionRequired is the error of a middleware used without Sessions.
+var errSessionRequired = errors.New("Sessions middleware is required")
+
+// Session is the session of a client. Values are encoded by encoding/gob, so
+// register your own types with gob.Register before store them.
+type Session struct {
+	ID     string
+	Values Map
+	// FlashMessages is the flash messages by kind, see Flash.
+	FlashMessages map[string][]string
+	Created       time.Time
+	Accessed      time.Time
+
+	changed   bool
+	destroyed bool
+	oldID     string
+}
+
+// newSession returns a new empty session.
+func newSession(now time.Time) *Session {
+	return &Session{
+		ID:            newSessionID(),
+		Values:        make(Map),
+		FlashMessages: make(map[string][]string),
+		Created:       now,
+		Accessed:      now,
+	}
+}
+
+// newSessionID returns a random session id.
+func newSessionID() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Get returns the value of k.
+func (s *Session) Get(k string) (interface{}, bool) {
+	v, ok := s.Values[k]
+	return v, ok
+}
+
+// GetString returns the string value of k, "" if not exists or not a string.
+func (s *Session) GetString(k string) string {
+	v, _ := s.Values[k].(string)
+	return v
+}
+
+// GetInt returns the int value of k, 0 if not exists or not an int.
+func (s *Session) GetInt(k string) int {
+	v, _ := s.Values[k].(int)
+	return v
+}
+
+// GetInt64 returns the int64 value of k, 0 if not exists or not an int64.
+func (s *Session) GetInt64(k string) int64 {
+	v, _ := s.Values[k].(int64)
+	return v
+}
+
+// GetBool returns the bool value of k, false if not exists or not a bool.
+func (s *Session) GetBool(k string) bool {
+	v, _ := s.Values[k].(bool)
+	return v
+}
+
+// Set sets the value of k.
+func (s *Session) Set(k string, v interface{}) {
+	s.Values[k] = v
+	s.changed = true
+}
+
+// Delete deletes k.
+func (s *Session) Delete(k string) {
+	delete(s.Values, k)
+	s.changed = true
+}
+
+// Clear deletes all the values.
+func (s *Session) Clear() {
+	s.Values = make(Map)
+	s.changed = true
+}
+
+// Flash adds a flash message of kind, which lives until it's read by Flashes.
+func (s *Session) Flash(kind, msg string) {
+	s.FlashMessages[kind] = append(s.FlashMessages[kind], msg)
+	s.changed = true
+}
+
+// Flashes returns and removes the flash messages of kind.
+func (s *Session) Flashes(kind string) []string {
+	msgs := s.FlashMessages[kind]
+	if len(msgs) != 0 {
+		delete(s.FlashMessages, kind)
+		s.changed = true
+	}
+	return msgs
+}
+
+// Regenerate changes the session id and keeps the values. Call it when the
+// privilege changes, e.g. login, to prevent session fixation.
+func (s *Session) Regenerate() {
+	if s.oldID == "" {
+		s.oldID = s.ID
+	}
+	s.ID = newSessionID()
+	s.changed = true
+}
+
+// Destroy deletes the session from the store and the client.
+func (s *Session) Destroy() {
+	s.destroyed = true
+}
+
+// encodeSession encodes s with encoding/gob.
+func encodeSession(s *Session) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := gob.NewEncoder(buf).Encode(s); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeSession decodes the session encoded by encodeSession.
+func decodeSession(b []byte) (*Session, error) {
+	s := new(Session)
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(s); err != nil {
+		return nil, err
+	}
+	if s.Values == nil {
+		s.Values = make(Map)
+	}
+	if s.FlashMessages == nil {
+		s.FlashMessages = make(map[string][]string)
+	}
+	return s, nil
+}
+
+// SessionStore stores the sessions.
+type SessionStore interface {
+	// Load returns the session of the cookie value v, nil if not found.
+	Load(v string) (*Session, error)
+	// Save saves s and returns the cookie value.
+	Save(s *Session) (string, error)
+	// Delete deletes the session with id.
+	Delete(id string) error
+}
+
+// maxCookieSize is the max size of a cookie value supported by browsers.
+const maxCookieSize = 4096
+
+// CookieStore is the SessionStore which stores the whole session in the
+// cookie.
+type CookieStore struct {
+	keyring *Keyring
+	encrypt bool
+}
+
+// NewCookieStore returns a CookieStore which signs the session with
+// HMAC-SHA256. The session is readable but can not be modified by clients.
+// The keys work as a Keyring.
+func NewCookieStore(keys ...[]byte) *CookieStore {
+	return &CookieStore{keyring: NewKeyring(keys...)}
+}
+
+// NewEncryptedCookieStore returns a CookieStore which encrypts the session
+// with AES-GCM. The keys work as a Keyring.
+func NewEncryptedCookieStore(keys ...[]byte) *CookieStore {
+	return &CookieStore{keyring: NewKeyring(keys...), encrypt: true}
+}
+
+// Load implements the SessionStore interface.
+func (cs *CookieStore) Load(v string) (*Session, error) {
+	var b string
+	var err error
+	if cs.encrypt {
+		b, err = cs.keyring.Decrypt("session", v)
+	} else {
+		b, err = cs.keyring.Verify("session", v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeSession([]byte(b))
+}
+
+// Save implements the SessionStore interface.
+func (cs *CookieStore) Save(s *Session) (string, error) {
+	b, err := encodeSession(s)
+	if err != nil {
+		return "", err
+	}
+	var v string
+	if cs.encrypt {
+		v, err = cs.keyring.Encrypt("session", string(b), time.Time{})
+	} else {
+		v = cs.keyring.Sign("session", string(b), time.Time{})
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(v) > maxCookieSize {
+		return "", errors.New("session is too large for a cookie")
+	}
+	return v, nil
+}
+
+// Delete implements the SessionStore interface.
+func (cs *CookieStore) Delete(id string) error {
+	return nil
+}
+
+// MemoryStore is the SessionStore which stores the sessions in memory, the
+// cookie only has the session id.
+type MemoryStore struct {
+	ttl      time.Duration
+	mu       sync.Mutex
+	sessions map[string]memorySession
+	swept    time.Time
+}
+
+// memorySession is a session stored in MemoryStore.
+type memorySession struct {
+	data   []byte
+	expire time.Time
+}
+
+// NewMemoryStore returns a MemoryStore, the sessions not loaded or saved in
+// ttl are evicted.
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		ttl:      ttl,
+		sessions: make(map[string]memorySession),
+		swept:    time.Now(),
+	}
+}
+
+// Load implements the SessionStore interface.
+func (ms *MemoryStore) Load(v string) (*Session, error) {
+	now := time.Now()
+	ms.mu.Lock()
+	m, ok := ms.sessions[v]
+	if ok && !now.After(m.expire) {
+		// the read-only sessions are not saved, the ttl slides on load
+		m.expire = now.Add(ms.ttl)
+		ms.sessions[v] = m
+	}
+	ms.mu.Unlock()
+	if !ok || now.After(m.expire) {
+		return nil, nil
+	}
+	return decodeSession(m.data)
+}
+
+// Save implements the SessionStore interface.
+func (ms *MemoryStore) Save(s *Session) (string, error) {
+	b, err := encodeSession(s)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.sessions[s.ID] = memorySession{data: b, expire: now.Add(ms.ttl)}
+	if now.Sub(ms.swept) > ms.ttl {
+		for id, m := range ms.sessions {
+			if now.After(m.expire) {
+				delete(ms.sessions, id)
+			}
+		}
+		ms.swept = now
+	}
+	return s.ID, nil
+}
+
+// Delete implements the SessionStore interface.
+func (ms *MemoryStore) Delete(id string) error {
+	ms.mu.Lock()
+	delete(ms.sessions, id)
+	ms.mu.Unlock()
+	return nil
+}
+
+// SessionConfig is the config of Sessions.
+type SessionConfig struct {
+	Store SessionStore
+	// CookieName is the name of the session cookie, default is
+	// "ctx_session".
+	CookieName string
+	// CookiePath is the path of the session cookie, default is "/".
+	CookiePath string
+	Domain     string
+	Secure     bool
+	// SameSite is the SameSite of the session cookie, default is Lax.
+	SameSite http.SameSite
+	// IdleTimeout expires the session which is not accessed in time, 0 means
+	// never.
+	IdleTimeout time.Duration
+	// AbsoluteTimeout expires the session which is created before time, 0
+	// means never.
+	AbsoluteTimeout time.Duration
+}
+
+// expired returns if s is expired at now.
+func (conf *SessionConfig) expired(s *Session, now time.Time) bool {
+	return (conf.IdleTimeout > 0 && now.Sub(s.Accessed) > conf.IdleTimeout) ||
+		(conf.AbsoluteTimeout > 0 &&
+			now.Sub(s.Created) > conf.AbsoluteTimeout)
+}
+
+// Sessions returns the middleware which loads the session of the request,
+// and saves it before the response is written. Use c.Session() to get it.
+func Sessions(cfg *SessionConfig) Handler {
+	conf := *cfg
+	if conf.Store == nil {
+		panic("[ctx] nil SessionStore")
+	}
+	if conf.CookieName == "" {
+		conf.CookieName = "ctx_session"
+	}
+	if conf.CookiePath == "" {
+		conf.CookiePath = "/"
+	}
+	if conf.SameSite == 0 {
+		conf.SameSite = http.SameSiteLaxMode
+	}
+	return func(c *Context) error {
+		if c.session != nil {
+			return nil
+		}
+		now := time.Now()
+		var s *Session
+		if cookie, err := c.Cookie(conf.CookieName); err == nil {
+			// an invalid cookie starts a new session
+			s, _ = conf.Store.Load(cookie.Value)
+		}
+		if s != nil && conf.expired(s, now) {
+			conf.Store.Delete(s.ID)
+			s = nil
+		}
+		if s == nil {
+			s = newSession(now)
+		} else if conf.IdleTimeout > 0 {
+			s.Accessed = now
+			s.changed = true
+		}
+		c.session = s
+		c.sessionConfig = &conf
+		c.BeforeWrite(c.saveSession)
+		return nil
+	}
+}
+
+// Session returns the session of the current request, nil if the Sessions
+// middleware is not used. The response varies by Cookie once it's read.
+func (c *Context) Session() *Session {
+	if c.session != nil {
+		c.vary("Cookie")
+	}
+	return c.session
+}
+
+// saveSession saves the session and sets the session cookie.
+func (c *Context) saveSession() {
+	s, conf := c.session, c.sessionConfig
+	cookie := &http.Cookie{
+		Name:     conf.CookieName,
+		Path:     conf.CookiePath,
+		Domain:   conf.Domain,
+		Secure:   conf.Secure,
+		HttpOnly: true,
+		SameSite: conf.SameSite,
+	}
+	if s.oldID != "" {
+		conf.Store.Delete(s.oldID)
+	}
+	if s.destroyed {
+		conf.Store.Delete(s.ID)
+		cookie.MaxAge = -1
+		c.SetCookie(cookie)
+		return
+	}
+	if !s.changed {
+		return
+	}
+	v, err := conf.Store.Save(s)
+	if err != nil {
+		log.Printf("%s save session error: %v\n", "[ctx]", err)
+		return
+	}
+	cookie.Value = v
+	if conf.AbsoluteTimeout > 0 {
+		cookie.Expires = s.Created.Add(conf.AbsoluteTimeout)
+	}
+	c.SetCookie(cookie)
+}
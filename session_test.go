@@ -0,0 +1,119 @@
+package ctx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sessionRequest(
+	target string,
+	cookie *http.Cookie,
+) (*httptest.ResponseRecorder, *http.Cookie) {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	res := httptest.NewRecorder()
+	routerIns.r.ServeHTTP(res, req)
+	for _, ck := range res.Result().Cookies() {
+		return res, ck
+	}
+	return res, cookie
+}
+
+func testSessionStore(t *testing.T, store SessionStore) {
+	resetRouter()
+	Use(Sessions(&SessionConfig{Store: store}))
+	GET("/set", func(c *Context) error {
+		c.Session().Set("n", 1)
+		c.Session().Flash("info", "hello")
+		return c.String("ok")
+	})
+	GET("/get", func(c *Context) error {
+		s := c.Session()
+		return c.Json(Map{"n": s.GetInt("n"), "flash": s.Flashes("info")})
+	})
+	GET("/login", func(c *Context) error {
+		c.Session().Regenerate()
+		return nil
+	})
+	GET("/logout", func(c *Context) error {
+		c.Session().Destroy()
+		return nil
+	})
+
+	res, cookie := sessionRequest("/get", nil)
+	assert.Nil(t, cookie)
+	assert.Equal(t, `{"flash":null,"n":0}`, res.Body.String())
+	assert.Equal(t, "Cookie", res.Header().Get("Vary"))
+
+	_, cookie = sessionRequest("/set", nil)
+	assert.NotNil(t, cookie)
+	assert.True(t, cookie.HttpOnly)
+	res, cookie = sessionRequest("/get", cookie)
+	assert.Equal(t, `{"flash":["hello"],"n":1}`, res.Body.String())
+	res, cookie = sessionRequest("/get", cookie)
+	assert.Equal(t, `{"flash":null,"n":1}`, res.Body.String())
+
+	// the handler writes nothing
+	_, newCookie := sessionRequest("/login", cookie)
+	assert.NotEqual(t, cookie.Value, newCookie.Value)
+	res, _ = sessionRequest("/get", newCookie)
+	assert.Equal(t, `{"flash":null,"n":1}`, res.Body.String())
+
+	_, deleted := sessionRequest("/logout", newCookie)
+	assert.Equal(t, -1, deleted.MaxAge)
+
+	cookie.Value = "x" + cookie.Value
+	res, _ = sessionRequest("/get", cookie)
+	assert.Equal(t, `{"flash":null,"n":0}`, res.Body.String())
+}
+
+func TestSessions(t *testing.T) {
+	testSessionStore(t, NewCookieStore([]byte("key")))
+	testSessionStore(t, NewEncryptedCookieStore(make([]byte, 32)))
+	testSessionStore(t, NewMemoryStore(time.Hour))
+}
+
+func TestMemoryStore(t *testing.T) {
+	ms := NewMemoryStore(time.Hour)
+	s := newSession(time.Now())
+	s.Set("k", "v")
+	id, err := ms.Save(s)
+	assert.NoError(t, err)
+	loaded, err := ms.Load(id)
+	assert.NoError(t, err)
+	assert.Equal(t, "v", loaded.GetString("k"))
+	// the ttl slides on load
+	ms.sessions[id] = memorySession{data: ms.sessions[id].data, expire: time.Now().Add(time.Second)}
+	_, err = ms.Load(id)
+	assert.NoError(t, err)
+	assert.True(t, ms.sessions[id].expire.After(time.Now().Add(time.Minute)))
+	assert.NoError(t, ms.Delete(id))
+	loaded, _ = ms.Load(id)
+	assert.Nil(t, loaded)
+
+	ms = NewMemoryStore(time.Millisecond)
+	id, _ = ms.Save(s)
+	time.Sleep(2 * time.Millisecond)
+	loaded, _ = ms.Load(id)
+	assert.Nil(t, loaded)
+	ms.Save(newSession(time.Now()))
+	assert.Len(t, ms.sessions, 1)
+}
+
+func TestSessionTimeout(t *testing.T) {
+	conf := &SessionConfig{IdleTimeout: time.Minute, AbsoluteTimeout: time.Hour}
+	now := time.Now()
+	s := newSession(now.Add(-time.Hour - time.Second))
+	assert.True(t, conf.expired(s, now))
+	s = newSession(now.Add(-time.Minute))
+	s.Accessed = now.Add(-time.Second)
+	assert.False(t, conf.expired(s, now))
+	s.Accessed = now.Add(-2 * time.Minute)
+	assert.True(t, conf.expired(s, now))
+}
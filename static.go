@@ -0,0 +1,179 @@
+package ctx
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StaticConfig is the config of Static.
+type StaticConfig struct {
+	// Index is the file served for a directory, default is "index.html".
+	Index string
+	// Browse lists the directory which has no index file.
+	Browse bool
+	// MaxAge sets the max-age of Cache-Control, 0 means no Cache-Control.
+	MaxAge time.Duration
+	// SPA serves the root index file for the paths not found, for single page
+	// applications.
+	SPA bool
+}
+
+// Static serves the files in root under prefix with GET and HEAD routes.
+// Use http.Dir to serve a directory of the OS.
+func Static(prefix string, root http.FileSystem, cfg ...*StaticConfig) *Route {
+	return routerIns.static(prefix, root, cfg...)
+}
+
+// StaticFS is same as Static, it serves fsys such as embed.FS under prefix.
+func StaticFS(prefix string, fsys fs.FS, cfg ...*StaticConfig) *Route {
+	return Static(prefix, http.FS(fsys), cfg...)
+}
+
+// g.Static is same as Static, it serves root under g.prefix+prefix.
+func (g *GroupRouter) Static(
+	prefix string,
+	root http.FileSystem,
+	cfg ...*StaticConfig,
+) *Route {
+	return g.r.static(g.prefix+prefix, root, cfg...)
+}
+
+// g.StaticFS is same as StaticFS, it serves fsys under g.prefix+prefix.
+func (g *GroupRouter) StaticFS(
+	prefix string,
+	fsys fs.FS,
+	cfg ...*StaticConfig,
+) *Route {
+	return g.Static(prefix, http.FS(fsys), cfg...)
+}
+
+// static registers the routes which serve root under prefix.
+func (r *router) static(
+	prefix string,
+	root http.FileSystem,
+	cfg ...*StaticConfig,
+) *Route {
+	conf := &StaticConfig{}
+	if len(cfg) != 0 && cfg[0] != nil {
+		conf = cfg[0]
+	}
+	if conf.Index == "" {
+		conf.Index = "index.html"
+	}
+	h := staticHandler(root, conf)
+	p := strings.TrimSuffix(prefix, "/") + "/*filepath"
+	r.push("HEAD", p, h)
+	return r.push("GET", p, h)
+}
+
+// staticHandler returns the handler which serves the files in root.
+func staticHandler(root http.FileSystem, conf *StaticConfig) Handler {
+	return func(c *Context) error {
+		name := c.Params("filepath")
+		if strings.Contains(name, "\x00") || strings.Contains(name, "\\") {
+			return ErrNotFound
+		}
+		// path.Clean removes all the ".." which may escape root.
+		name = path.Clean("/" + name)
+		f, err := root.Open(name)
+		if err != nil && conf.SPA && errors.Is(err, fs.ErrNotExist) {
+			f, err = root.Open("/" + conf.Index)
+		}
+		if err != nil {
+			return ErrNotFound
+		}
+		defer f.Close()
+		stat, err := f.Stat()
+		if err != nil {
+			return ErrNotFound
+		}
+		if stat.IsDir() {
+			if !strings.HasSuffix(c.Path(), "/") {
+				u := *c.Req.URL
+				u.Path += "/"
+				u.RawPath = ""
+				return c.Redirect(u.String(), http.StatusMovedPermanently)
+			}
+			index, err := root.Open(path.Join(name, conf.Index))
+			if err != nil {
+				if conf.Browse {
+					return c.listDir(f)
+				}
+				return ErrNotFound
+			}
+			defer index.Close()
+			f = index
+		}
+		if conf.MaxAge > 0 {
+			c.ResHeader().Set(
+				"Cache-Control",
+				"public, max-age="+strconv.Itoa(int(conf.MaxAge.Seconds())),
+			)
+		}
+		return c.serveContent(f)
+	}
+}
+
+// ServeFS response the current request with the file `name` in fsys. It
+// handles Range and conditional requests like ServeFile.
+func (c *Context) ServeFS(fsys http.FileSystem, name string) error {
+	f, err := fsys.Open(path.Clean("/" + name))
+	if err != nil {
+		return e("response file error", err)
+	}
+	defer f.Close()
+	return c.serveContent(f)
+}
+
+// serveContent response the current request with f.
+func (c *Context) serveContent(f http.File) error {
+	stat, err := f.Stat()
+	if err != nil {
+		return e("response file error", err)
+	}
+	if stat.IsDir() {
+		return e(
+			"response file error",
+			fmt.Errorf("%s is a directory", stat.Name()),
+		)
+	}
+	http.ServeContent(c.Res, c.Req, stat.Name(), stat.ModTime(), f)
+	c.done = true
+	return nil
+}
+
+// listDir response the current request with the entries of dir.
+func (c *Context) listDir(dir http.File) error {
+	entries, err := dir.Readdir(-1)
+	if err != nil {
+		return e("list directory error", err)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	b := new(strings.Builder)
+	b.WriteString("<!doctype html>\n<pre>\n")
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		u := url.URL{Path: name}
+		fmt.Fprintf(
+			b, "<a href=\"%s\">%s</a>\n",
+			html.EscapeString(u.String()), html.EscapeString(name),
+		)
+	}
+	b.WriteString("</pre>\n")
+	c.ResHeader().Set("Content-Type", "text/html; charset=utf-8")
+	return c.String(b.String())
+}
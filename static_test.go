@@ -0,0 +1,72 @@
+package ctx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testFS = fstest.MapFS{
+	"index.html":     {Data: []byte("index")},
+	"a.txt":          {Data: []byte("a")},
+	"dir/b.txt":      {Data: []byte("b")},
+	"sub/index.html": {Data: []byte("sub")},
+}
+
+func serve(method, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	res := httptest.NewRecorder()
+	routerIns.r.ServeHTTP(res, req)
+	return res
+}
+
+func TestStatic(t *testing.T) {
+	resetRouter()
+	StaticFS("/s", testFS, &StaticConfig{MaxAge: time.Hour})
+	g := Group("/g")
+	g.StaticFS("/", testFS, &StaticConfig{Browse: true, SPA: true})
+
+	res := serve(http.MethodGet, "/s/a.txt")
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "a", res.Body.String())
+	assert.Equal(t, "public, max-age=3600", res.Header().Get("Cache-Control"))
+
+	res = serve(http.MethodHead, "/s/a.txt")
+	assert.Equal(t, 200, res.Code)
+
+	res = serve(http.MethodGet, "/s/")
+	assert.Equal(t, "index", res.Body.String())
+	res = serve(http.MethodGet, "/s/sub")
+	assert.Equal(t, http.StatusMovedPermanently, res.Code)
+	assert.Equal(t, "/s/sub/", res.Header().Get("Location"))
+	res = serve(http.MethodGet, "/s/sub/")
+	assert.Equal(t, "sub", res.Body.String())
+
+	res = serve(http.MethodGet, "/s/dir/")
+	assert.Equal(t, 404, res.Code)
+	res = serve(http.MethodGet, "/s/none")
+	assert.Equal(t, 404, res.Code)
+	res = serve(http.MethodGet, "/s/../static_test.go")
+	assert.NotEqual(t, 200, res.Code)
+
+	res = serve(http.MethodGet, "/g/dir/")
+	assert.Equal(t, 200, res.Code)
+	assert.Contains(t, res.Body.String(), `<a href="b.txt">b.txt</a>`)
+	res = serve(http.MethodGet, "/g/app/users/1")
+	assert.Equal(t, "index", res.Body.String())
+}
+
+func TestServeFS(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Range", "bytes=0-0")
+	res := httptest.NewRecorder()
+	c := getContext(res, req)
+	assert.NoError(t, c.ServeFS(http.FS(testFS), "index.html"))
+	assert.Equal(t, http.StatusPartialContent, res.Code)
+	assert.Equal(t, "i", res.Body.String())
+	assert.Error(t, c.ServeFS(http.FS(testFS), "none"))
+}
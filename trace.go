@@ -0,0 +1,272 @@
+package ctx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceID is the W3C trace id.
+type TraceID [16]byte
+
+// String returns the lowercase hex of id.
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid returns if id is not all zeros.
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+// SpanID is the W3C parent id.
+type SpanID [8]byte
+
+// String returns the lowercase hex of id.
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid returns if id is not all zeros.
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// SpanEvent is an event in a span, e.g. an exception.
+type SpanEvent struct {
+	Name       string
+	Time       time.Time
+	Attributes Map
+}
+
+// Span is the server span of a request.
+type Span struct {
+	Name     string
+	TraceID  TraceID
+	SpanID   SpanID
+	ParentID SpanID
+	// TraceState is the tracestate propagated from the parent.
+	TraceState string
+	Sampled    bool
+	Start      time.Time
+	End        time.Time
+	Attributes Map
+	Events     []SpanEvent
+	// Error is the status message of the failed span, "" if it's not failed.
+	Error string
+
+	mu sync.Mutex
+}
+
+// SetAttribute sets the attribute k of s.
+func (s *Span) SetAttribute(k string, v interface{}) {
+	s.mu.Lock()
+	s.Attributes[k] = v
+	s.mu.Unlock()
+}
+
+// AddEvent adds an event to s.
+func (s *Span) AddEvent(name string, attrs Map) {
+	s.mu.Lock()
+	s.Events = append(s.Events, SpanEvent{Name: name, Time: time.Now(), Attributes: attrs})
+	s.mu.Unlock()
+}
+
+// RecordError adds an exception event of err and marks s failed.
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.AddEvent("exception", Map{
+		"exception.type":    fmt.Sprintf("%T", err),
+		"exception.message": err.Error(),
+	})
+	s.mu.Lock()
+	s.Error = err.Error()
+	s.mu.Unlock()
+}
+
+// TraceParent returns the traceparent header of s.
+func (s *Span) TraceParent() string {
+	flags := "00"
+	if s.Sampled {
+		flags = "01"
+	}
+	return "00-" + s.TraceID.String() + "-" + s.SpanID.String() + "-" + flags
+}
+
+// Inject sets the traceparent and the tracestate headers of s to h, for the
+// outgoing requests.
+func (s *Span) Inject(h http.Header) {
+	h.Set("traceparent", s.TraceParent())
+	if s.TraceState != "" {
+		h.Set("tracestate", s.TraceState)
+	} else {
+		h.Del("tracestate")
+	}
+}
+
+// spanKey is the context key of the span.
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of parent with s.
+func ContextWithSpan(parent context.Context, s *Span) context.Context {
+	return context.WithValue(parent, spanKey{}, s)
+}
+
+// SpanFromContext returns the span in ctx, nil if not exists.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// Span returns the span of the current request, nil if the Tracing
+// middleware is not used.
+func (c *Context) Span() *Span {
+	return SpanFromContext(c.Req.Context())
+}
+
+// SpanExporter exports the ended spans. Export is called when the request is
+// handled, so it should not block.
+type SpanExporter interface {
+	Export(s *Span) error
+}
+
+// TracingConfig is the config of Tracing.
+type TracingConfig struct {
+	Exporter SpanExporter
+	// Sample decides if the trace started by the request is sampled, default
+	// is sampling all. The sampled flag of traceparent is followed if exists.
+	Sample func(c *Context) bool
+}
+
+// Tracing returns the middleware which creates a server span named after the
+// method and the route pattern for every request, following the W3C Trace Context in the
+// traceparent and tracestate headers. The span is stored in c.Req.Context(),
+// and ended with the status, the error passed to ErrorHandler and the panic
+// passed to PanicHandler.
+func Tracing(cfg *TracingConfig) Handler {
+	conf := TracingConfig{}
+	if cfg != nil {
+		conf = *cfg
+	}
+	return func(c *Context) error {
+		s := &Span{
+			Name:  c.Method(),
+			Start: time.Now(),
+			Attributes: Map{
+				"http.request.method": c.Method(),
+				"url.path":            c.Path(),
+				"url.scheme":          c.Scheme(),
+				"server.address":      c.RealHost(),
+				"client.address":      c.ClientIP(),
+			},
+		}
+		// the path is unbounded without a route, only the method names the span
+		if c.route != nil {
+			s.Name += " " + c.route.Path
+			s.Attributes["http.route"] = c.route.Path
+		}
+		header := c.ReqHeader()
+		if traceID, parentID, sampled, ok := parseTraceParent(header.Get("traceparent")); ok {
+			s.TraceID, s.ParentID, s.Sampled = traceID, parentID, sampled
+			s.TraceState = strings.Join(header.Values("tracestate"), ",")
+		} else {
+			rand.Read(s.TraceID[:])
+			s.Sampled = conf.Sample == nil || conf.Sample(c)
+		}
+		rand.Read(s.SpanID[:])
+		c.Req = c.Req.WithContext(ContextWithSpan(c.Req.Context(), s))
+		c.AfterWrite(func() {
+			status := c.ResStatus()
+			s.SetAttribute("http.response.status_code", status)
+			if c.err != nil {
+				s.RecordError(c.err)
+			}
+			if c.recovered != nil {
+				s.RecordError(fmt.Errorf("panic: %v", c.recovered))
+			}
+			s.mu.Lock()
+			if s.Error == "" && status >= 500 {
+				s.Error = http.StatusText(status)
+			}
+			s.End = time.Now()
+			s.mu.Unlock()
+			if s.Sampled && conf.Exporter != nil {
+				if err := conf.Exporter.Export(s); err != nil {
+					log.Printf("%s export span error: %v\n", "[ctx]", err)
+				}
+			}
+		})
+		return nil
+	}
+}
+
+// parseTraceParent parses the traceparent header. The higher versions are
+// parsed as version 00.
+func parseTraceParent(v string) (traceID TraceID, parentID SpanID, sampled, ok bool) {
+	v = strings.TrimSpace(v)
+	if len(v) < 55 || (len(v) > 55 && v[55] != '-') ||
+		v[2] != '-' || v[35] != '-' || v[52] != '-' {
+		return
+	}
+	version, err := hex.DecodeString(v[:2])
+	if err != nil || version[0] == 0xff || (version[0] == 0 && len(v) != 55) {
+		return
+	}
+	if !isLowerHex(v[:55]) {
+		return
+	}
+	hex.Decode(traceID[:], []byte(v[3:35]))
+	hex.Decode(parentID[:], []byte(v[36:52]))
+	flags, err := hex.DecodeString(v[53:55])
+	if err != nil || !traceID.IsValid() || !parentID.IsValid() {
+		return
+	}
+	return traceID, parentID, flags[0]&1 == 1, true
+}
+
+// isLowerHex returns if s only has lowercase hex and '-'.
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if !(ch >= '0' && ch <= '9') && !(ch >= 'a' && ch <= 'f') && ch != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// InMemoryExporter keeps the exported spans in memory, for tests.
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+// Export implements the SpanExporter interface.
+func (ex *InMemoryExporter) Export(s *Span) error {
+	ex.mu.Lock()
+	ex.spans = append(ex.spans, s)
+	ex.mu.Unlock()
+	return nil
+}
+
+// Spans returns the exported spans.
+func (ex *InMemoryExporter) Spans() []*Span {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+	return append([]*Span(nil), ex.spans...)
+}
+
+// Reset removes the exported spans.
+func (ex *InMemoryExporter) Reset() {
+	ex.mu.Lock()
+	ex.spans = nil
+	ex.mu.Unlock()
+}
@@ -0,0 +1,100 @@
+package ctx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	traceID, parentID, sampled, ok := parseTraceParent(
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.True(t, ok)
+	assert.True(t, sampled)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", parentID.String())
+
+	_, _, sampled, ok = parseTraceParent(
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future")
+	assert.True(t, ok)
+	assert.False(t, sampled)
+
+	for _, v := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0x",
+	} {
+		_, _, _, ok := parseTraceParent(v)
+		assert.False(t, ok, v)
+	}
+}
+
+func TestTracing(t *testing.T) {
+	resetRouter()
+	exporter := new(InMemoryExporter)
+	Use(Tracing(&TracingConfig{Exporter: exporter}))
+	GET("/users/:id", func(c *Context) error {
+		assert.NotNil(t, SpanFromContext(c.Req.Context()))
+		h := http.Header{}
+		c.Span().Inject(h)
+		return c.String(h.Get("traceparent") + " " + h.Get("tracestate"))
+	})
+	GET("/error", func(c *Context) error {
+		return errors.New("failed")
+	})
+	GET("/panic", func(c *Context) error {
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("tracestate", "vendor=value")
+	res := httptest.NewRecorder()
+	routerIns.r.ServeHTTP(res, req)
+	spans := exporter.Spans()
+	assert.Len(t, spans, 1)
+	s := spans[0]
+	assert.Equal(t, "GET /users/:id", s.Name)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", s.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", s.ParentID.String())
+	assert.Equal(t, "vendor=value", s.TraceState)
+	assert.Equal(t, 200, s.Attributes["http.response.status_code"])
+	assert.Equal(t, "", s.Error)
+	assert.False(t, s.End.Before(s.Start))
+	assert.Equal(t, s.TraceParent()+" vendor=value", res.Body.String())
+
+	exporter.Reset()
+	routerIns.r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/error", nil))
+	routerIns.r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+	spans = exporter.Spans()
+	assert.Len(t, spans, 2)
+	assert.True(t, spans[0].TraceID.IsValid())
+	assert.False(t, spans[0].ParentID.IsValid())
+	assert.Equal(t, "failed", spans[0].Error)
+	assert.Equal(t, "exception", spans[0].Events[0].Name)
+	assert.Equal(t, "panic: boom", spans[1].Error)
+	assert.Equal(t, 500, spans[1].Attributes["http.response.status_code"])
+
+	// not sampled
+	exporter.Reset()
+	req = httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	routerIns.r.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Len(t, exporter.Spans(), 0)
+
+	// without a route
+	exporter.Reset()
+	Handler(Tracing(&TracingConfig{Exporter: exporter})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	spans = exporter.Spans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "GET", spans[0].Name)
+	assert.NotContains(t, spans[0].Attributes, "http.route")
+	assert.Equal(t, "/users/1", spans[0].Attributes["url.path"])
+}
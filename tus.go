@@ -0,0 +1,485 @@
+package ctx
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tusVersion is the supported version of the tus protocol.
+const tusVersion = "1.0.0"
+
+// ErrUploadNotFound is returned by UploadStore if the upload not exists.
+var ErrUploadNotFound = errors.New("upload not found")
+
+// UploadInfo is the state of a resumable upload.
+type UploadInfo struct {
+	ID string `json:"id"`
+	// Size is the total size of the upload.
+	Size int64 `json:"size"`
+	// Offset is the size of the received data.
+	Offset   int64             `json:"offset"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Created  time.Time         `json:"created"`
+	// Expires is the time an unfinished upload expires.
+	Expires time.Time `json:"expires"`
+}
+
+// Done returns if all the data is received.
+func (info *UploadInfo) Done() bool {
+	return info.Offset >= info.Size
+}
+
+// expired returns if the unfinished upload is expired.
+func (info *UploadInfo) expired() bool {
+	return !info.Done() && !info.Expires.IsZero() && time.Now().After(info.Expires)
+}
+
+// UploadStore is the storage of the resumable uploads.
+type UploadStore interface {
+	// Create creates the upload of info.
+	Create(info *UploadInfo) error
+	// Info returns the upload id, ErrUploadNotFound if not exists.
+	Info(id string) (*UploadInfo, error)
+	// Append writes r to the upload of info at info.Offset, and saves info
+	// with the new Offset. It returns the written size, which is saved even
+	// if reading r fails, so the client can resume from it.
+	Append(info *UploadInfo, r io.Reader) (int64, error)
+	// Delete removes the upload id.
+	Delete(id string) error
+	// List returns the ids of all uploads.
+	List() ([]string, error)
+}
+
+// TusConfig is the config of Tus.
+type TusConfig struct {
+	Store UploadStore
+	// MaxSize is the max size of an upload, 0 means no limit.
+	MaxSize int64
+	// Expiration is the time an unfinished upload expires after its last
+	// PATCH, default is 24h.
+	Expiration time.Duration
+	// OnComplete is called once when all the data of the upload is received.
+	OnComplete func(c *Context, info *UploadInfo) error
+}
+
+// Tus registers the routes of the tus resumable upload protocol under path,
+// with the core protocol and the creation, expiration and termination
+// extensions. e.g. ctx.Tus("/files", &ctx.TusConfig{Store: store})
+//
+//	OPTIONS path      the server capabilities
+//	POST    path      creates an upload, the Location is path/:id
+//	HEAD    path/:id  the offset of the upload
+//	PATCH   path/:id  appends the data at the offset
+//	DELETE  path/:id  terminates the upload
+func Tus(path string, cfg *TusConfig) []*Route {
+	return routerIns.tus(path, cfg)
+}
+
+// g.Tus is same as Tus, it registers the routes under g.prefix+path.
+func (g *GroupRouter) Tus(path string, cfg *TusConfig) []*Route {
+	return g.r.tus(g.prefix+path, cfg)
+}
+
+// tus registers the tus routes under path.
+func (r *router) tus(path string, cfg *TusConfig) []*Route {
+	t := &tusHandler{conf: *cfg, path: strings.TrimSuffix(path, "/")}
+	if t.conf.Expiration <= 0 {
+		t.conf.Expiration = 24 * time.Hour
+	}
+	t.locks = make(map[string]bool)
+	return []*Route{
+		r.push("OPTIONS", t.path, t.options).Hidden(),
+		r.push("POST", t.path, t.create).Hidden(),
+		r.push("HEAD", t.path+"/:id", t.head).Hidden(),
+		r.push("PATCH", t.path+"/:id", t.patch).Hidden(),
+		r.push("DELETE", t.path+"/:id", t.terminate).Hidden(),
+	}
+}
+
+// tusHandler is the handlers of the tus routes.
+type tusHandler struct {
+	conf TusConfig
+	path string
+
+	mu    sync.Mutex
+	locks map[string]bool
+}
+
+// lock locks the upload id, it returns false if the upload is locked.
+func (t *tusHandler) lock(id string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.locks[id] {
+		return false
+	}
+	t.locks[id] = true
+	return true
+}
+
+// unlock unlocks the upload id.
+func (t *tusHandler) unlock(id string) {
+	t.mu.Lock()
+	delete(t.locks, id)
+	t.mu.Unlock()
+}
+
+// begin sets the tus headers and checks the version of the client.
+func (t *tusHandler) begin(c *Context) error {
+	c.ResHeader().Set("Tus-Resumable", tusVersion)
+	if c.ReqHeader().Get("Tus-Resumable") != tusVersion {
+		c.ResHeader().Set("Tus-Version", tusVersion)
+		return NewHTTPError(http.StatusPreconditionFailed, "unsupported tus version")
+	}
+	return nil
+}
+
+// info returns the upload of the request, the expired upload is removed.
+func (t *tusHandler) info(c *Context) (*UploadInfo, error) {
+	id := c.Params("id")
+	info, err := t.conf.Store.Info(id)
+	if err == ErrUploadNotFound {
+		return nil, NewHTTPError(http.StatusNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.expired() {
+		t.conf.Store.Delete(id)
+		return nil, NewHTTPError(http.StatusGone)
+	}
+	return info, nil
+}
+
+// setExpires sets the Upload-Expires header of the unfinished upload.
+func setExpires(c *Context, info *UploadInfo) {
+	if !info.Done() {
+		c.ResHeader().Set("Upload-Expires", info.Expires.UTC().Format(http.TimeFormat))
+	}
+}
+
+// options responses the capabilities of the server.
+func (t *tusHandler) options(c *Context) error {
+	header := c.ResHeader()
+	header.Set("Tus-Resumable", tusVersion)
+	header.Set("Tus-Version", tusVersion)
+	header.Set("Tus-Extension", "creation,expiration,termination")
+	if t.conf.MaxSize > 0 {
+		header.Set("Tus-Max-Size", strconv.FormatInt(t.conf.MaxSize, 10))
+	}
+	c.SetStatusCode(http.StatusNoContent)
+	return nil
+}
+
+// create creates an upload.
+func (t *tusHandler) create(c *Context) error {
+	if err := t.begin(c); err != nil {
+		return err
+	}
+	size, err := strconv.ParseInt(c.ReqHeader().Get("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		return NewHTTPError(http.StatusBadRequest, "invalid Upload-Length")
+	}
+	if t.conf.MaxSize > 0 && size > t.conf.MaxSize {
+		return NewHTTPError(http.StatusRequestEntityTooLarge)
+	}
+	metadata, err := parseUploadMetadata(c.ReqHeader().Get("Upload-Metadata"))
+	if err != nil {
+		return NewHTTPError(http.StatusBadRequest, "invalid Upload-Metadata")
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	now := time.Now()
+	info := &UploadInfo{
+		ID:       hex.EncodeToString(id),
+		Size:     size,
+		Metadata: metadata,
+		Created:  now,
+		Expires:  now.Add(t.conf.Expiration),
+	}
+	if err := t.conf.Store.Create(info); err != nil {
+		return err
+	}
+	if info.Done() && t.conf.OnComplete != nil {
+		if err := t.conf.OnComplete(c, info); err != nil {
+			return err
+		}
+	}
+	c.ResHeader().Set("Location", t.path+"/"+info.ID)
+	setExpires(c, info)
+	c.SetStatusCode(http.StatusCreated)
+	return nil
+}
+
+// head responses the offset of the upload.
+func (t *tusHandler) head(c *Context) error {
+	if err := t.begin(c); err != nil {
+		return err
+	}
+	info, err := t.info(c)
+	if err != nil {
+		return err
+	}
+	header := c.ResHeader()
+	header.Set("Cache-Control", "no-store")
+	header.Set("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	header.Set("Upload-Length", strconv.FormatInt(info.Size, 10))
+	if len(info.Metadata) != 0 {
+		header.Set("Upload-Metadata", formatUploadMetadata(info.Metadata))
+	}
+	setExpires(c, info)
+	c.SetStatusCode(http.StatusOK)
+	return nil
+}
+
+// patch appends the data to the upload.
+func (t *tusHandler) patch(c *Context) error {
+	if err := t.begin(c); err != nil {
+		return err
+	}
+	if c.ReqHeader().Get("Content-Type") != "application/offset+octet-stream" {
+		return NewHTTPError(http.StatusUnsupportedMediaType)
+	}
+	offset, err := strconv.ParseInt(c.ReqHeader().Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return NewHTTPError(http.StatusBadRequest, "invalid Upload-Offset")
+	}
+	id := c.Params("id")
+	if !t.lock(id) {
+		return NewHTTPError(http.StatusLocked)
+	}
+	defer t.unlock(id)
+	info, err := t.info(c)
+	if err != nil {
+		return err
+	}
+	// the upload is completed only once, so OnComplete runs only once
+	if info.Done() {
+		return NewHTTPError(http.StatusForbidden, "upload is completed")
+	}
+	if offset != info.Offset {
+		return NewHTTPError(http.StatusConflict, "mismatched Upload-Offset")
+	}
+	info.Expires = time.Now().Add(t.conf.Expiration)
+	body := http.MaxBytesReader(c.Res, c.ReqBody(), info.Size-info.Offset)
+	_, err = t.conf.Store.Append(info, body)
+	// the data more than the length is dropped, the upload is still done
+	var mbe *http.MaxBytesError
+	tooLarge := errors.As(err, &mbe)
+	if err != nil && !tooLarge {
+		return err
+	}
+	if info.Done() && t.conf.OnComplete != nil {
+		if err := t.conf.OnComplete(c, info); err != nil {
+			return err
+		}
+	}
+	if tooLarge {
+		return NewHTTPError(http.StatusRequestEntityTooLarge)
+	}
+	c.ResHeader().Set("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	setExpires(c, info)
+	c.SetStatusCode(http.StatusNoContent)
+	return nil
+}
+
+// terminate removes the upload.
+func (t *tusHandler) terminate(c *Context) error {
+	if err := t.begin(c); err != nil {
+		return err
+	}
+	id := c.Params("id")
+	if !t.lock(id) {
+		return NewHTTPError(http.StatusLocked)
+	}
+	defer t.unlock(id)
+	if _, err := t.info(c); err != nil {
+		return err
+	}
+	if err := t.conf.Store.Delete(id); err != nil {
+		return err
+	}
+	c.SetStatusCode(http.StatusNoContent)
+	return nil
+}
+
+// CleanupUploads removes the expired unfinished uploads in store. Call it
+// periodically.
+func CleanupUploads(store UploadStore) error {
+	ids, err := store.List()
+	if err != nil {
+		return e("cleanup uploads error", err)
+	}
+	for _, id := range ids {
+		info, err := store.Info(id)
+		if err != nil || !info.expired() {
+			continue
+		}
+		if err := store.Delete(id); err != nil {
+			return e("cleanup uploads error", err)
+		}
+	}
+	return nil
+}
+
+// parseUploadMetadata parses the Upload-Metadata header, the comma separated
+// pairs of the key and the base64 value.
+func parseUploadMetadata(s string) (map[string]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	m := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if k == "" {
+			return nil, errors.New("empty metadata key")
+		}
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, err
+		}
+		m[k] = string(b)
+	}
+	return m, nil
+}
+
+// formatUploadMetadata returns the Upload-Metadata header of m.
+func formatUploadMetadata(m map[string]string) string {
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		pair := k
+		if v != "" {
+			pair += " " + base64.StdEncoding.EncodeToString([]byte(v))
+		}
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// FileUploadStore is the UploadStore in a directory of the OS. The data of
+// an upload is in the file named its id, and the info is in id.info.
+type FileUploadStore struct {
+	dir string
+}
+
+// NewFileUploadStore returns a FileUploadStore in dir, it creates dir if not
+// exists.
+func NewFileUploadStore(dir string) (*FileUploadStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, e("new file upload store error", err)
+	}
+	return &FileUploadStore{dir: dir}, nil
+}
+
+// validUploadID returns if id is made by Tus, so it's safe in a path.
+func validUploadID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// Path returns the path of the data of the upload id.
+func (s *FileUploadStore) Path(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+// saveInfo writes info atomically.
+func (s *FileUploadStore) saveInfo(info *UploadInfo) error {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	tmp := s.Path(info.ID) + ".info.tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.Path(info.ID)+".info")
+}
+
+// Create implements the UploadStore interface.
+func (s *FileUploadStore) Create(info *UploadInfo) error {
+	f, err := os.OpenFile(s.Path(info.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return e("create upload error", err)
+	}
+	f.Close()
+	return e("create upload error", s.saveInfo(info))
+}
+
+// Info implements the UploadStore interface.
+func (s *FileUploadStore) Info(id string) (*UploadInfo, error) {
+	if !validUploadID(id) {
+		return nil, ErrUploadNotFound
+	}
+	b, err := os.ReadFile(s.Path(id) + ".info")
+	if os.IsNotExist(err) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, e("upload info error", err)
+	}
+	info := new(UploadInfo)
+	if err := json.Unmarshal(b, info); err != nil {
+		return nil, e("upload info error", err)
+	}
+	return info, nil
+}
+
+// Append implements the UploadStore interface.
+func (s *FileUploadStore) Append(info *UploadInfo, r io.Reader) (int64, error) {
+	f, err := os.OpenFile(s.Path(info.ID), os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, e("append upload error", err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(info.Offset, io.SeekStart); err != nil {
+		return 0, e("append upload error", err)
+	}
+	n, copyErr := io.Copy(f, r)
+	info.Offset += n
+	if err := s.saveInfo(info); err != nil {
+		return n, e("append upload error", err)
+	}
+	return n, copyErr
+}
+
+// Delete implements the UploadStore interface.
+func (s *FileUploadStore) Delete(id string) error {
+	if !validUploadID(id) {
+		return ErrUploadNotFound
+	}
+	os.Remove(s.Path(id) + ".info")
+	if err := os.Remove(s.Path(id)); err != nil && !os.IsNotExist(err) {
+		return e("delete upload error", err)
+	}
+	return nil
+}
+
+// List implements the UploadStore interface.
+func (s *FileUploadStore) List() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, e("list uploads error", err)
+	}
+	ids := []string{}
+	for _, entry := range entries {
+		if id, ok := strings.CutSuffix(entry.Name(), ".info"); ok && validUploadID(id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
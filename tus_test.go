@@ -0,0 +1,138 @@
+package ctx
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tusRequest(method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	res := httptest.NewRecorder()
+	routerIns.r.ServeHTTP(res, req)
+	return res
+}
+
+func TestTus(t *testing.T) {
+	resetRouter()
+	store, err := NewFileUploadStore(t.TempDir())
+	assert.NoError(t, err)
+	var completed *UploadInfo
+	completes := 0
+	Group("/api").Tus("/files", &TusConfig{
+		Store:   store,
+		MaxSize: 100,
+		OnComplete: func(c *Context, info *UploadInfo) error {
+			completed = info
+			completes++
+			return nil
+		},
+	})
+
+	res := tusRequest("OPTIONS", "/api/files", "", nil)
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "creation,expiration,termination", res.Header().Get("Tus-Extension"))
+	assert.Equal(t, "100", res.Header().Get("Tus-Max-Size"))
+
+	res = tusRequest("POST", "/api/files", "", map[string]string{"Tus-Resumable": "0.2.0"})
+	assert.Equal(t, http.StatusPreconditionFailed, res.Code)
+	res = tusRequest("POST", "/api/files", "", map[string]string{"Upload-Length": "101"})
+	assert.Equal(t, http.StatusRequestEntityTooLarge, res.Code)
+	res = tusRequest("POST", "/api/files", "", nil)
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = tusRequest("POST", "/api/files", "", map[string]string{
+		"Upload-Length":   "11",
+		"Upload-Metadata": "filename d29ybGQudHh0,private",
+	})
+	assert.Equal(t, http.StatusCreated, res.Code)
+	assert.Equal(t, "1.0.0", res.Header().Get("Tus-Resumable"))
+	assert.NotEmpty(t, res.Header().Get("Upload-Expires"))
+	location := res.Header().Get("Location")
+	assert.True(t, strings.HasPrefix(location, "/api/files/"))
+	id := strings.TrimPrefix(location, "/api/files/")
+
+	patch := map[string]string{
+		"Content-Type":  "application/offset+octet-stream",
+		"Upload-Offset": "0",
+	}
+	res = tusRequest("PATCH", location, "hello", patch)
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "5", res.Header().Get("Upload-Offset"))
+
+	res = tusRequest("PATCH", location, "hello", patch)
+	assert.Equal(t, http.StatusConflict, res.Code)
+	res = tusRequest("PATCH", location, "hello", map[string]string{"Upload-Offset": "5"})
+	assert.Equal(t, http.StatusUnsupportedMediaType, res.Code)
+
+	res = tusRequest("HEAD", location, "", nil)
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "5", res.Header().Get("Upload-Offset"))
+	assert.Equal(t, "11", res.Header().Get("Upload-Length"))
+	assert.Equal(t, "filename d29ybGQudHh0,private", res.Header().Get("Upload-Metadata"))
+	assert.Equal(t, "no-store", res.Header().Get("Cache-Control"))
+
+	// more than the length
+	patch["Upload-Offset"] = "5"
+	res = tusRequest("PATCH", location, " world!!", patch)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, res.Code)
+	res = tusRequest("HEAD", location, "", nil)
+	assert.Equal(t, "11", res.Header().Get("Upload-Offset"))
+	assert.NotNil(t, completed)
+	assert.Equal(t, "world.txt", completed.Metadata["filename"])
+	b, _ := os.ReadFile(store.Path(id))
+	assert.Equal(t, "hello world", string(b))
+
+	// the completed upload can't be patched again
+	patch["Upload-Offset"] = "11"
+	res = tusRequest("PATCH", location, "", patch)
+	assert.Equal(t, http.StatusForbidden, res.Code)
+	res = tusRequest("PATCH", location, "!", patch)
+	assert.Equal(t, http.StatusForbidden, res.Code)
+	assert.Equal(t, 1, completes)
+
+	res = tusRequest("DELETE", location, "", nil)
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	res = tusRequest("HEAD", location, "", nil)
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	res = tusRequest("HEAD", "/api/files/..%2f..%2fetc", "", nil)
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func TestTusExpiration(t *testing.T) {
+	resetRouter()
+	store, _ := NewFileUploadStore(t.TempDir())
+	Tus("/files", &TusConfig{Store: store, Expiration: time.Hour})
+	res := tusRequest("POST", "/files", "", map[string]string{"Upload-Length": "10"})
+	location := res.Header().Get("Location")
+	id := strings.TrimPrefix(location, "/files/")
+
+	info, _ := store.Info(id)
+	info.Expires = time.Now().Add(-time.Second)
+	store.Append(info, strings.NewReader(""))
+	res = tusRequest("HEAD", location, "", nil)
+	assert.Equal(t, http.StatusGone, res.Code)
+	_, err := store.Info(id)
+	assert.Equal(t, ErrUploadNotFound, err)
+
+	res = tusRequest("POST", "/files", "", map[string]string{"Upload-Length": "10"})
+	id = strings.TrimPrefix(res.Header().Get("Location"), "/files/")
+	res = tusRequest("POST", "/files", "", map[string]string{"Upload-Length": "0"})
+	done := strings.TrimPrefix(res.Header().Get("Location"), "/files/")
+	info, _ = store.Info(id)
+	info.Expires = time.Now().Add(-time.Second)
+	store.Append(info, io.LimitReader(nil, 0))
+	assert.NoError(t, CleanupUploads(store))
+	ids, _ := store.List()
+	assert.Equal(t, []string{done}, ids)
+}